@@ -0,0 +1,380 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+
+	channelzpb "google.golang.org/grpc/channelz/grpc_channelz_v1"
+)
+
+// AdminService describes an exposed administrative or debug service
+type AdminService struct {
+	Service     string   `json:"service"`
+	Description string   `json:"description"`
+	Severity    string   `json:"severity"`
+	Methods     []string `json:"methods,omitempty"`
+}
+
+// ChannelzInfo holds internal topology pulled from an exposed channelz service
+type ChannelzInfo struct {
+	Servers  []string         `json:"servers,omitempty"`
+	Channels []string         `json:"channels,omitempty"`
+	Sockets  []ChannelzSocket `json:"sockets,omitempty"`
+}
+
+// ChannelzSocket describes a single connection reported by channelz
+type ChannelzSocket struct {
+	ID       int64    `json:"id"`
+	Local    string   `json:"local,omitempty"`
+	Remote   string   `json:"remote,omitempty"`
+	TLS      string   `json:"tls,omitempty"`
+	PeerCert []string `json:"peer_cert,omitempty"`
+}
+
+// adminPattern is a known admin/debug service and the risk of exposing it
+type adminPattern struct {
+	Service     string
+	Methods     []string
+	Description string
+	Severity    string
+}
+
+// Admin and debug services that should never be reachable from outside
+var adminPatterns = []adminPattern{
+	{
+		Service:     "grpc.channelz.v1.Channelz",
+		Methods:     []string{"GetTopChannels", "GetServers", "GetServer", "GetServerSockets", "GetChannel", "GetSubchannel", "GetSocket"},
+		Description: "channelz exposes internal channels, peer addresses and TLS details",
+		Severity:    "high",
+	},
+	{
+		Service:     "envoy.service.status.v3.ClientStatusDiscoveryService",
+		Methods:     []string{"FetchClientStatus", "StreamClientStatus"},
+		Description: "CSDS exposes the xDS configuration of the server's clients",
+		Severity:    "high",
+	},
+	{
+		Service:     "envoy.service.status.v2.ClientStatusDiscoveryService",
+		Methods:     []string{"FetchClientStatus", "StreamClientStatus"},
+		Description: "CSDS exposes the xDS configuration of the server's clients",
+		Severity:    "high",
+	},
+	{
+		Service:     "grpc.instrumentation.v1alpha.Monitoring",
+		Methods:     []string{"GetCanonicalRpcStats", "GetStats", "WatchStats", "GetRequestTraces", "GetCustomMonitoringData"},
+		Description: "monitoring service exposes RPC statistics and request traces",
+		Severity:    "high",
+	},
+	{
+		Service:     "envoy.service.discovery.v3.AggregatedDiscoveryService",
+		Methods:     []string{"StreamAggregatedResources", "DeltaAggregatedResources"},
+		Description: "xDS control plane is reachable and may serve routing and cluster configuration",
+		Severity:    "medium",
+	},
+	{
+		Service:     "grpc.lb.v1.LoadBalancer",
+		Methods:     []string{"BalanceLoad"},
+		Description: "grpclb balancer is reachable and may reveal backend addresses",
+		Severity:    "medium",
+	},
+	{
+		Service:     "grpc.lookup.v1.RouteLookupService",
+		Methods:     []string{"RouteLookup"},
+		Description: "route lookup service is reachable and may reveal internal targets",
+		Severity:    "medium",
+	},
+	{
+		Service:     "grpc.gcp.HandshakerService",
+		Methods:     []string{"DoHandshake"},
+		Description: "ALTS handshaker service is reachable",
+		Severity:    "medium",
+	},
+	{
+		Service:     "grpc.testing.TestService",
+		Methods:     []string{"EmptyCall", "UnaryCall", "StreamingOutputCall", "FullDuplexCall"},
+		Description: "interop test service is deployed",
+		Severity:    "low",
+	},
+	{
+		Service:     "grpc.testing.XdsUpdateClientConfigureService",
+		Methods:     []string{"Configure"},
+		Description: "xDS interop control service can reconfigure client RPC behaviour",
+		Severity:    "high",
+	},
+	{
+		Service:     "grpc.testing.XdsUpdateHealthService",
+		Methods:     []string{"SetServing", "SetNotServing"},
+		Description: "xDS interop control service can flip the server's health status",
+		Severity:    "high",
+	},
+	{
+		Service:     "grpc.testing.LoadBalancerStatsService",
+		Methods:     []string{"GetClientStats", "GetClientAccumulatedStats"},
+		Description: "interop load balancer statistics service is deployed",
+		Severity:    "low",
+	},
+	{
+		Service:     "grpc.testing.ReconnectService",
+		Methods:     []string{"Start", "Stop"},
+		Description: "interop reconnect test service is deployed",
+		Severity:    "low",
+	},
+	{
+		Service:     "grpc.testing.WorkerService",
+		Methods:     []string{"RunServer", "RunClient", "CoreCount", "QuitWorker"},
+		Description: "benchmark worker service can start workloads and shut the worker down",
+		Severity:    "high",
+	},
+	{
+		Service:     "grpc.testing.BenchmarkService",
+		Methods:     []string{"UnaryCall", "StreamingCall"},
+		Description: "benchmark service is deployed",
+		Severity:    "low",
+	},
+}
+
+// checkAdminServices looks for exposed admin and debug services
+func (s *Scanner) checkAdminServices(ctx context.Context) {
+	for _, pattern := range adminPatterns {
+		if !s.checkService(ctx, pattern.Service, pattern.Methods[0]) {
+			continue
+		}
+
+		s.addService(pattern.Service, "admin")
+		admin := AdminService{
+			Service:     pattern.Service,
+			Description: pattern.Description,
+			Severity:    pattern.Severity,
+		}
+		for _, method := range pattern.Methods {
+			if s.checkMethod(ctx, pattern.Service, method) {
+				s.addMethod(pattern.Service, method)
+				admin.Methods = append(admin.Methods, method)
+			}
+		}
+		s.addAdminService(admin)
+		fmt.Printf("[!] Admin service exposed: %s (%s)\n", pattern.Service, pattern.Severity)
+	}
+
+	// Reflection may list test services we don't have a pattern for
+	for _, service := range s.servicesSnapshot() {
+		if strings.HasPrefix(service, "grpc.testing.") && !s.hasAdminService(service) {
+			s.addAdminService(AdminService{
+				Service:     service,
+				Description: "gRPC test service is deployed",
+				Severity:    "low",
+			})
+			fmt.Printf("[!] Admin service exposed: %s (low)\n", service)
+		}
+	}
+
+	if s.hasAdminService("grpc.channelz.v1.Channelz") {
+		s.queryChannelz(ctx)
+	}
+}
+
+// queryChannelz pulls servers, channels and sockets from an exposed channelz service
+func (s *Scanner) queryChannelz(ctx context.Context) {
+	client := channelzpb.NewChannelzClient(s.recording())
+	info := &ChannelzInfo{}
+	socketIDs := []int64{}
+
+	servers, err := channelzServers(ctx, client)
+	if err != nil {
+		if s.verbose {
+			fmt.Printf("   [-] channelz GetServers failed: %v\n", err)
+		}
+		return
+	}
+
+	for _, server := range servers {
+		serverID := server.GetRef().GetServerId()
+		listen := []string{}
+		for _, ref := range server.GetListenSocket() {
+			listen = append(listen, ref.GetName())
+			socketIDs = append(socketIDs, ref.GetSocketId())
+		}
+		info.Servers = append(info.Servers, fmt.Sprintf("server %d (%d calls) listening on %s",
+			serverID, server.GetData().GetCallsStarted(), strings.Join(listen, ", ")))
+
+		for _, ref := range channelzServerSockets(ctx, client, serverID) {
+			socketIDs = append(socketIDs, ref.GetSocketId())
+		}
+	}
+
+	channels, err := channelzTopChannels(ctx, client)
+	if err == nil {
+		for _, channel := range channels {
+			info.Channels = append(info.Channels, fmt.Sprintf("%s (%s)",
+				channel.GetData().GetTarget(), channel.GetData().GetState().GetState()))
+
+			for _, ref := range channel.GetSocketRef() {
+				socketIDs = append(socketIDs, ref.GetSocketId())
+			}
+			for _, ref := range channel.GetSubchannelRef() {
+				sub, err := client.GetSubchannel(ctx, &channelzpb.GetSubchannelRequest{SubchannelId: ref.GetSubchannelId()})
+				if err != nil {
+					continue
+				}
+				for _, socketRef := range sub.GetSubchannel().GetSocketRef() {
+					socketIDs = append(socketIDs, socketRef.GetSocketId())
+				}
+			}
+		}
+	}
+
+	seen := make(map[int64]bool)
+	for _, id := range socketIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		resp, err := client.GetSocket(ctx, &channelzpb.GetSocketRequest{SocketId: id})
+		if err != nil {
+			continue
+		}
+		info.Sockets = append(info.Sockets, describeSocket(id, resp.GetSocket()))
+	}
+
+	s.resultMutex.Lock()
+	s.result.Channelz = info
+	s.resultMutex.Unlock()
+
+	fmt.Printf("[!] channelz leaked %d servers, %d channels and %d sockets\n",
+		len(info.Servers), len(info.Channels), len(info.Sockets))
+}
+
+// maxChannelzPages bounds each paginated channelz listing. Servers return
+// up to 100 entries per page, so this covers thousands of connections.
+const maxChannelzPages = 50
+
+// channelzServers lists every server, following pages until End. An error
+// is returned only if the first page fails.
+func channelzServers(ctx context.Context, client channelzpb.ChannelzClient) ([]*channelzpb.Server, error) {
+	var servers []*channelzpb.Server
+	var start int64
+	for page := 0; page < maxChannelzPages; page++ {
+		resp, err := client.GetServers(ctx, &channelzpb.GetServersRequest{StartServerId: start})
+		if err != nil {
+			if page == 0 {
+				return nil, err
+			}
+			break
+		}
+		servers = append(servers, resp.GetServer()...)
+		if resp.GetEnd() || len(resp.GetServer()) == 0 {
+			break
+		}
+		start = resp.GetServer()[len(resp.GetServer())-1].GetRef().GetServerId() + 1
+	}
+	return servers, nil
+}
+
+// channelzServerSockets lists every connection of a server, following pages
+// until End
+func channelzServerSockets(ctx context.Context, client channelzpb.ChannelzClient, serverID int64) []*channelzpb.SocketRef {
+	var refs []*channelzpb.SocketRef
+	var start int64
+	for page := 0; page < maxChannelzPages; page++ {
+		resp, err := client.GetServerSockets(ctx, &channelzpb.GetServerSocketsRequest{ServerId: serverID, StartSocketId: start})
+		if err != nil {
+			break
+		}
+		refs = append(refs, resp.GetSocketRef()...)
+		if resp.GetEnd() || len(resp.GetSocketRef()) == 0 {
+			break
+		}
+		start = resp.GetSocketRef()[len(resp.GetSocketRef())-1].GetSocketId() + 1
+	}
+	return refs
+}
+
+// channelzTopChannels lists every top-level channel, following pages until
+// End. An error is returned only if the first page fails.
+func channelzTopChannels(ctx context.Context, client channelzpb.ChannelzClient) ([]*channelzpb.Channel, error) {
+	var channels []*channelzpb.Channel
+	var start int64
+	for page := 0; page < maxChannelzPages; page++ {
+		resp, err := client.GetTopChannels(ctx, &channelzpb.GetTopChannelsRequest{StartChannelId: start})
+		if err != nil {
+			if page == 0 {
+				return nil, err
+			}
+			break
+		}
+		channels = append(channels, resp.GetChannel()...)
+		if resp.GetEnd() || len(resp.GetChannel()) == 0 {
+			break
+		}
+		start = resp.GetChannel()[len(resp.GetChannel())-1].GetRef().GetChannelId() + 1
+	}
+	return channels, nil
+}
+
+// describeSocket converts a channelz socket into addresses and TLS details
+func describeSocket(id int64, socket *channelzpb.Socket) ChannelzSocket {
+	result := ChannelzSocket{
+		ID:     id,
+		Local:  formatChannelzAddress(socket.GetLocal()),
+		Remote: formatChannelzAddress(socket.GetRemote()),
+	}
+
+	if tls := socket.GetSecurity().GetTls(); tls != nil {
+		result.TLS = tls.GetStandardName()
+		if result.TLS == "" {
+			result.TLS = tls.GetOtherName()
+		}
+		if cert, err := x509.ParseCertificate(tls.GetRemoteCertificate()); err == nil {
+			result.PeerCert = append(result.PeerCert, "CN="+cert.Subject.CommonName)
+			result.PeerCert = append(result.PeerCert, cert.DNSNames...)
+		}
+	} else if other := socket.GetSecurity().GetOther(); other != nil {
+		result.TLS = other.GetName()
+	}
+
+	return result
+}
+
+func formatChannelzAddress(addr *channelzpb.Address) string {
+	if addr == nil {
+		return ""
+	}
+	if tcp := addr.GetTcpipAddress(); tcp != nil {
+		return net.JoinHostPort(net.IP(tcp.GetIpAddress()).String(), fmt.Sprint(tcp.GetPort()))
+	}
+	if uds := addr.GetUdsAddress(); uds != nil {
+		return "unix:" + uds.GetFilename()
+	}
+	return addr.GetOtherAddress().GetName()
+}
+
+// Thread-safe admin result updates
+func (s *Scanner) addAdminService(admin AdminService) {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	s.result.AdminServices = append(s.result.AdminServices, admin)
+}
+
+func (s *Scanner) hasAdminService(service string) bool {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	for _, admin := range s.result.AdminServices {
+		if admin.Service == service {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) servicesSnapshot() []string {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	return append([]string(nil), s.result.AvailableServices...)
+}
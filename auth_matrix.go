@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stringList is a flag.Value that collects every occurrence of a repeated flag
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// Credential is a named set of metadata sent with a probe
+type Credential struct {
+	Name     string
+	Metadata []string // alternating key/value pairs
+}
+
+// AuthMatrix holds the status code of every method for every credential set
+type AuthMatrix struct {
+	Credentials []string        `json:"credentials"`
+	Rows        []AuthMatrixRow `json:"rows"`
+}
+
+// AuthMatrixRow is one method's status codes, in AuthMatrix.Credentials order
+type AuthMatrixRow struct {
+	Method string   `json:"method"`
+	Codes  []string `json:"codes"`
+}
+
+// buildCredentials turns the credential flags into named credential sets
+func buildCredentials(bearers, apiKeys, basics, headers []string) ([]Credential, error) {
+	var creds []Credential
+
+	add := func(kind string, values []string, toMetadata func(string) ([]string, error)) error {
+		for i, value := range values {
+			md, err := toMetadata(value)
+			if err != nil {
+				return err
+			}
+			name := kind
+			if len(values) > 1 {
+				name = fmt.Sprintf("%s#%d", kind, i+1)
+			}
+			creds = append(creds, Credential{Name: name, Metadata: md})
+		}
+		return nil
+	}
+
+	if err := add("bearer", bearers, func(v string) ([]string, error) {
+		return []string{"authorization", "Bearer " + strings.TrimPrefix(v, "Bearer ")}, nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := add("api-key", apiKeys, func(v string) ([]string, error) {
+		return []string{"x-api-key", v}, nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := add("basic", basics, func(v string) ([]string, error) {
+		if !strings.Contains(v, ":") {
+			return nil, fmt.Errorf("basic credential must be user:password")
+		}
+		return []string{"authorization", "Basic " + base64.StdEncoding.EncodeToString([]byte(v))}, nil
+	}); err != nil {
+		return nil, err
+	}
+	if err := add("header", headers, func(v string) ([]string, error) {
+		parts := strings.SplitN(v, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("header credential must be \"name: value\"")
+		}
+		return []string{strings.ToLower(strings.TrimSpace(parts[0])), strings.TrimSpace(parts[1])}, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return creds, nil
+}
+
+// withCredential attaches a credential's metadata to an outgoing context
+func withCredential(ctx context.Context, cred Credential) context.Context {
+	if len(cred.Metadata) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, cred.Metadata...)
+}
+
+// confirmedMethods lists every confirmed, non-standard method as Service/Method
+func (s *Scanner) confirmedMethods() []string {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	var methods []string
+	for service, names := range s.result.MethodsFound {
+		for _, method := range names {
+			fullMethod := service + "/" + method
+			if !isStandardService(fullMethod) {
+				methods = append(methods, fullMethod)
+			}
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// runAuthMatrix calls every discovered method (confirmed by probing or listed
+// by reflection) without credentials and with each credential set, recording
+// the resulting status codes
+func (s *Scanner) runAuthMatrix() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	methods := s.discoveredMethods(ctx, false)
+	cancel()
+	if len(methods) == 0 {
+		fmt.Println("\n[-] Auth matrix skipped: no discovered methods to test")
+		return
+	}
+
+	creds := append([]Credential{{Name: "none"}}, s.credentials...)
+	fmt.Printf("\n[+] Building auth matrix: %d methods x %d credential sets...\n", len(methods), len(creds))
+
+	matrix := &AuthMatrix{}
+	for _, cred := range creds {
+		matrix.Credentials = append(matrix.Credentials, cred.Name)
+	}
+
+	for _, fullMethod := range methods {
+		service, method := splitFullMethod(fullMethod)
+		row := AuthMatrixRow{Method: fullMethod}
+		for _, cred := range creds {
+			ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+			err := s.invoke(withCredential(ctx, cred), service, method)
+			cancel()
+			row.Codes = append(row.Codes, status.Code(err).String())
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+
+	s.resultMutex.Lock()
+	s.result.AuthMatrix = matrix
+	s.resultMutex.Unlock()
+}
+
+// printAuthMatrix renders the auth matrix as a table
+func (s *Scanner) printAuthMatrix() {
+	matrix := s.result.AuthMatrix
+	if matrix == nil || len(matrix.Rows) == 0 {
+		return
+	}
+
+	methodWidth := len("Method")
+	for _, row := range matrix.Rows {
+		if len(row.Method) > methodWidth {
+			methodWidth = len(row.Method)
+		}
+	}
+	widths := make([]int, len(matrix.Credentials))
+	for i, name := range matrix.Credentials {
+		widths[i] = len(name)
+		for _, row := range matrix.Rows {
+			if len(row.Codes[i]) > widths[i] {
+				widths[i] = len(row.Codes[i])
+			}
+		}
+	}
+
+	fmt.Println("\nAuth Matrix:")
+	header := fmt.Sprintf("   %-*s", methodWidth, "Method")
+	for i, name := range matrix.Credentials {
+		header += fmt.Sprintf("  %-*s", widths[i], name)
+	}
+	fmt.Println(strings.TrimRight(header, " "))
+	for _, row := range matrix.Rows {
+		line := fmt.Sprintf("   %-*s", methodWidth, row.Method)
+		for i, code := range row.Codes {
+			line += fmt.Sprintf("  %-*s", widths[i], code)
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+//go:embed data/grpc_wordlist.txt data/auth_wordlist.txt data/cloud_wordlist.txt
+var builtinFS embed.FS
+
+// builtinPrefix selects an embedded wordlist, as in -wordlist=builtin:default
+const builtinPrefix = "builtin:"
+
+// builtinWordlist is one of the curated wordlists compiled into the binary
+type builtinWordlist struct {
+	Name        string
+	File        string
+	Description string
+}
+
+var builtinWordlists = []builtinWordlist{
+	{"default", "data/grpc_wordlist.txt", "Common service names and methods across naming conventions"},
+	{"auth", "data/auth_wordlist.txt", "Authentication, identity, token and permission services"},
+	{"cloud", "data/cloud_wordlist.txt", "Cloud-native infrastructure (Envoy, etcd, Kubernetes, containerd) and Google Cloud APIs"},
+}
+
+// openWordlist opens a wordlist file, or an embedded one for builtin:NAME
+func openWordlist(path string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(path, builtinPrefix) {
+		return os.Open(path)
+	}
+	name := strings.TrimPrefix(path, builtinPrefix)
+	for _, builtin := range builtinWordlists {
+		if builtin.Name == name {
+			return builtinFS.Open(builtin.File)
+		}
+	}
+	return nil, fmt.Errorf("unknown built-in wordlist %q (see 'wordlist list')", name)
+}
+
+// runWordlistList implements "wordlist list": describe the built-in wordlists
+func runWordlistList() {
+	fmt.Println("Built-in wordlists (use -wordlist=builtin:NAME):")
+	fmt.Println()
+	for _, builtin := range builtinWordlists {
+		set := newWordlistSet()
+		if err := set.readFile(builtinPrefix + builtin.Name); err != nil {
+			fmt.Printf("  %-8s %v\n", builtin.Name, err)
+			continue
+		}
+		fmt.Printf("  %-8s %s\n", builtin.Name, builtin.Description)
+		fmt.Printf("  %-8s %d services, %d package prefixes, %d global methods\n",
+			"", len(set.entries), len(set.packages), len(set.globalMethods))
+	}
+	fmt.Println()
+	fmt.Println("Any built-in can also be merged with your own lists:")
+	fmt.Println("  grpc-scanner wordlist merge -output=combined.txt builtin:default my_wordlist.txt")
+}
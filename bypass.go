@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// BypassAttempt records a path variant whose status differed from the baseline
+type BypassAttempt struct {
+	Method     string `json:"method"`
+	Variant    string `json:"variant"`
+	Path       string `json:"path"`
+	Baseline   string `json:"baseline"`
+	Authorized string `json:"authorized,omitempty"` // canonical path with a credential
+	Code       string `json:"code"`
+	Bypass     bool   `json:"bypass"`
+}
+
+// pathVariant is an alternative way of addressing a protected method
+type pathVariant struct {
+	Name string
+	Path string
+	Opts []grpc.CallOption
+	// Transport sends the variant over another wire protocol, changing the
+	// content-type while keeping the :path
+	Transport string
+}
+
+// subtypeCodec encodes protobuf under another content-subtype name, so a
+// call goes out as application/grpc+<name>
+type subtypeCodec struct{ name string }
+
+func (subtypeCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+	return proto.Marshal(msg)
+}
+
+// Unmarshal discards the reply, which may not be protobuf at all under the
+// other subtype; the probes only look at the status
+func (subtypeCodec) Unmarshal(data []byte, v interface{}) error {
+	return nil
+}
+
+func (c subtypeCodec) Name() string {
+	return c.name
+}
+
+// Substrings that authorization interceptors commonly allow-list
+var allowListSubstrings = []string{
+	"grpc.health.v1.Health",
+	"grpc.reflection",
+	"Health",
+	"Public",
+	"Login",
+	"AuthService",
+}
+
+// generatePathVariants builds path variants for a protected Service/Method
+func generatePathVariants(service, method string, allowList []string) []pathVariant {
+	path := "/" + service + "/" + method
+	variants := []pathVariant{
+		{Name: "missing leading slash", Path: service + "/" + method},
+		{Name: "double leading slash", Path: "/" + path},
+		{Name: "double slash before method", Path: "/" + service + "//" + method},
+		{Name: "trailing slash", Path: path + "/"},
+		{Name: "lowercase method", Path: "/" + service + "/" + strings.ToLower(method)},
+		{Name: "uppercase method", Path: "/" + service + "/" + strings.ToUpper(method)},
+		{Name: "lowercase path", Path: strings.ToLower(path)},
+		{Name: "swapped first letter case", Path: "/" + service + "/" + swapFirstCase(method)},
+		{Name: "url-encoded method char", Path: "/" + service + "/" + fmt.Sprintf("%%%02X", method[0]) + method[1:]},
+		{Name: "url-encoded dot", Path: "/" + strings.ReplaceAll(service, ".", "%2E") + "/" + method},
+		{Name: "url-encoded slashes", Path: "%2F" + service + "%2F" + method},
+		{Name: "dot segment", Path: "/" + service + "/./" + method},
+		// Same :path, different content-type: filters and interceptors that
+		// match on "application/grpc" exactly may let these through
+		{Name: "content-subtype proto", Path: path, Opts: []grpc.CallOption{grpc.CallContentSubtype("proto")}},
+		{Name: "content-subtype json", Path: path, Opts: []grpc.CallOption{grpc.ForceCodec(subtypeCodec{"json"})}},
+		{Name: "unknown content-subtype", Path: path, Opts: []grpc.CallOption{grpc.ForceCodec(subtypeCodec{"x-scan"})}},
+		{Name: "grpc-web content-type", Path: path, Transport: transportGRPCWeb},
+		{Name: "grpc-web-text content-type", Path: path, Transport: transportGRPCWebText},
+		{Name: "connect content-type", Path: path, Transport: transportConnect},
+	}
+
+	for _, allow := range allowList {
+		variants = append(variants,
+			pathVariant{Name: "allow-listed query " + allow, Path: path + "?" + allow},
+			pathVariant{Name: "allow-listed matrix param " + allow, Path: path + ";" + allow},
+			pathVariant{Name: "allow-listed fragment " + allow, Path: path + "#" + allow},
+			pathVariant{Name: "allow-listed traversal " + allow, Path: "/" + allow + "/.." + path},
+			pathVariant{Name: "allow-listed package prefix " + allow, Path: "/" + allow + "." + service + "/" + method},
+		)
+	}
+
+	return variants
+}
+
+func swapFirstCase(name string) string {
+	if name == "" {
+		return name
+	}
+	first := name[:1]
+	if strings.ToUpper(first) == first {
+		return strings.ToLower(first) + name[1:]
+	}
+	return strings.ToUpper(first) + name[1:]
+}
+
+// probeMethodStatus calls every discovered method that has no recorded status
+// yet (reflected methods are never probed during discovery) without
+// credentials and records the answer
+func (s *Scanner) probeMethodStatus() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	methods := s.discoveredMethods(ctx, false)
+	cancel()
+
+	for _, fullMethod := range methods {
+		s.resultMutex.Lock()
+		_, known := s.result.MethodStatus[fullMethod]
+		s.resultMutex.Unlock()
+		if known {
+			continue
+		}
+
+		service, method := splitFullMethod(fullMethod)
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		err := s.invoke(ctx, service, method)
+		cancel()
+		if code := status.Code(err); reachedHandler(code) || isAuthRejection(code) {
+			s.recordMethodStatus(service, method, err)
+		}
+	}
+}
+
+// protectedMethods returns discovered methods whose anonymous probe was rejected
+func (s *Scanner) protectedMethods() map[string]string {
+	s.probeMethodStatus()
+
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	protected := make(map[string]string)
+	for fullMethod, probe := range s.result.MethodStatus {
+		if probe.Code == codes.Unauthenticated.String() || probe.Code == codes.PermissionDenied.String() {
+			protected[fullMethod] = probe.Code
+		}
+	}
+	return protected
+}
+
+// publicServices returns services that answered an anonymous probe with OK,
+// which are likely to appear in an interceptor's allow-list
+func (s *Scanner) publicServices() []string {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	seen := make(map[string]bool)
+	for fullMethod, probe := range s.result.MethodStatus {
+		service, _ := splitFullMethod(fullMethod)
+		if probe.Code == codes.OK.String() && !isStandardService(fullMethod) {
+			seen[service] = true
+		}
+	}
+
+	services := make([]string, 0, len(seen))
+	for service := range seen {
+		services = append(services, service)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// runBypassProbes replays protected methods with path variants and records any
+// variant whose status differs from the Unauthenticated/PermissionDenied baseline
+func (s *Scanner) runBypassProbes() {
+	protected := s.protectedMethods()
+	if len(protected) == 0 {
+		fmt.Println("\n[-] Bypass probes skipped: no methods rejected anonymous calls")
+		return
+	}
+
+	allowList := append([]string{}, allowListSubstrings...)
+	for _, service := range s.publicServices() {
+		allowList = append(allowList, service, service[strings.LastIndex(service, ".")+1:])
+	}
+
+	methods := make([]string, 0, len(protected))
+	for fullMethod := range protected {
+		methods = append(methods, fullMethod)
+	}
+	sort.Strings(methods)
+
+	fmt.Printf("\n[+] Probing authorization bypasses on %d protected methods...\n", len(methods))
+
+	// Connections for the variants sent over another wire protocol
+	conns := make(map[string]Conn)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for _, fullMethod := range methods {
+		baseline := protected[fullMethod]
+		service, method := splitFullMethod(fullMethod)
+		authorized := s.authorizedCode(service, method)
+
+		for _, variant := range generatePathVariants(service, method, allowList) {
+			var conn grpc.ClientConnInterface = s.conn
+			if variant.Transport != "" {
+				if variant.Transport == s.transport {
+					continue
+				}
+				if conns[variant.Transport] == nil {
+					conns[variant.Transport] = newHTTPConn(s.target, variant.Transport, s.useTLS)
+				}
+				conn = conns[variant.Transport]
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+			err := s.invokeOn(ctx, conn, variant.Path, variant.Opts...)
+			cancel()
+
+			st, ok := status.FromError(err)
+			if !ok || st.Code().String() == baseline {
+				continue
+			}
+
+			// A variant bypasses authorization when it reaches the handler,
+			// and, if a credential got through on the canonical path, gets
+			// the same answer that authorized call did
+			bypass := reachedHandler(st.Code())
+			if authorized != "" {
+				bypass = bypass && st.Code().String() == authorized
+			}
+
+			attempt := BypassAttempt{
+				Method:     fullMethod,
+				Variant:    variant.Name,
+				Path:       variant.Path,
+				Baseline:   baseline,
+				Authorized: authorized,
+				Code:       st.Code().String(),
+				Bypass:     bypass,
+			}
+			s.resultMutex.Lock()
+			s.result.BypassAttempts = append(s.result.BypassAttempts, attempt)
+			s.resultMutex.Unlock()
+
+			if attempt.Bypass {
+				evidence := fmt.Sprintf("%s (%q) returned %s instead of %s", variant.Name, variant.Path, attempt.Code, baseline)
+				if authorized != "" {
+					evidence += fmt.Sprintf(", matching the %s of an authorized call", authorized)
+				}
+				fmt.Printf("[!] Possible bypass: %s via %s (%s -> %s)\n", fullMethod, variant.Name, baseline, attempt.Code)
+				s.addFinding(Finding{
+					ID:          "authorization-path-bypass",
+					Title:       "Authorization bypass via path variant",
+					Severity:    "critical",
+					Method:      fullMethod,
+					Evidence:    evidence,
+					Remediation: "Authorize on the exact, canonical method name (e.g. info.FullMethod equality) and reject malformed paths.",
+				})
+			} else if s.verbose {
+				fmt.Printf("   [-] %s via %s: %s\n", fullMethod, variant.Name, attempt.Code)
+			}
+		}
+	}
+}
+
+// authorizedCode calls the canonical path with each credential set and
+// returns the first code that reached the handler, or "" if none did
+func (s *Scanner) authorizedCode(service, method string) string {
+	for _, cred := range s.credentials {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		code := status.Code(s.invoke(withCredential(ctx, cred), service, method))
+		cancel()
+		if reachedHandler(code) {
+			return code.String()
+		}
+	}
+	return ""
+}
+
+// reachedHandler reports whether a status code means the request got past
+// routing and authorization to the method handler: OK, or a code handlers
+// return for a request they did not like. Internal and Unknown do not count,
+// since proxies and servers also return them for malformed requests.
+func reachedHandler(code codes.Code) bool {
+	switch code {
+	case codes.OK, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.FailedPrecondition, codes.OutOfRange, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// printBypassAttempts summarizes bypass probing in the human output
+func (s *Scanner) printBypassAttempts() {
+	if len(s.result.BypassAttempts) == 0 {
+		return
+	}
+
+	bypasses := 0
+	for _, attempt := range s.result.BypassAttempts {
+		if attempt.Bypass {
+			bypasses++
+		}
+	}
+
+	fmt.Printf("\nBypass Probes: %d variants changed status, %d reached the handler\n",
+		len(s.result.BypassAttempts), bypasses)
+	for _, attempt := range s.result.BypassAttempts {
+		if attempt.Bypass || s.verbose {
+			fmt.Printf("   %s  %-40q %s -> %s\n", attempt.Method, attempt.Path, attempt.Baseline, attempt.Code)
+		}
+	}
+}
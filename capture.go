@@ -0,0 +1,600 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+)
+
+// CapturedCall is one gRPC, gRPC-Web or Connect request observed in a HAR
+// or pcap capture, kept for replay with -call -replay
+type CapturedCall struct {
+	Method      string              `json:"method"`
+	ContentType string              `json:"content_type"`
+	Codec       string              `json:"codec"`
+	Metadata    map[string][]string `json:"metadata,omitempty"`
+	Payload     []byte              `json:"payload,omitempty"`
+	// Messages holds every request message of a client or bidi streaming
+	// call that sent more than one; Payload is the first of them
+	Messages [][]byte `json:"messages,omitempty"`
+	Source   string   `json:"source"`
+}
+
+// captureMethodPattern matches the /pkg.Service/Method tail of a request path
+var captureMethodPattern = regexp.MustCompile(`/((?:[A-Za-z_]\w*\.)*[A-Za-z_]\w*)/([A-Za-z_]\w*)$`)
+
+// Request headers that belong to the transport rather than the application,
+// so they are not replayed as metadata
+var captureSkipHeaders = map[string]bool{
+	"host": true, "content-type": true, "content-length": true, "te": true,
+	"connection": true, "accept": true, "accept-encoding": true, "accept-language": true,
+	"user-agent": true, "x-user-agent": true, "x-grpc-web": true, "grpc-timeout": true,
+	"grpc-accept-encoding": true, "grpc-encoding": true, "connect-protocol-version": true,
+	"connect-timeout-ms": true, "connect-accept-encoding": true, "connect-content-encoding": true,
+	"origin": true, "referer": true, "pragma": true, "cache-control": true, "priority": true,
+}
+
+// newCapturedCall recognizes a gRPC-family request by path and content type
+// and unwraps its request messages
+func newCapturedCall(path, contentType string, headers map[string][]string, body []byte, source string) (*CapturedCall, bool) {
+	match := captureMethodPattern.FindStringSubmatch(path)
+	if match == nil {
+		return nil, false
+	}
+	contentType = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+
+	framed := strings.HasPrefix(contentType, "application/grpc") || strings.HasPrefix(contentType, "application/connect+")
+	connectUnary := (contentType == "application/proto" || contentType == "application/json") && len(headers["connect-protocol-version"]) > 0
+	if !framed && !connectUnary {
+		return nil, false
+	}
+
+	call := &CapturedCall{
+		Method:      match[1] + "/" + match[2],
+		ContentType: contentType,
+		Codec:       "proto",
+		Metadata:    make(map[string][]string),
+		Source:      source,
+	}
+	if strings.Contains(contentType, "json") {
+		call.Codec = "json"
+	}
+	for key, values := range headers {
+		if !strings.HasPrefix(key, ":") && !captureSkipHeaders[key] && !strings.HasPrefix(key, "sec-") {
+			call.Metadata[key] = values
+		}
+	}
+
+	if strings.HasPrefix(contentType, "application/grpc-web-text") {
+		decoded, err := io.ReadAll(&webTextReader{r: bufio.NewReader(bytes.NewReader(body))})
+		if err != nil {
+			return call, true
+		}
+		body = decoded
+	}
+	if !framed {
+		call.Payload = body
+		return call, true
+	}
+	// Compressed messages (flag bit 0) cannot be replayed as captured and
+	// end the list, as does a truncated capture
+	var messages [][]byte
+	for len(body) >= 5 && body[0]&0x01 == 0 {
+		end := 5 + int(binary.BigEndian.Uint32(body[1:5]))
+		if end > len(body) {
+			end = len(body)
+		}
+		messages = append(messages, body[5:end])
+		body = body[end:]
+	}
+	if len(messages) > 0 {
+		call.Payload = messages[0]
+	}
+	if len(messages) > 1 {
+		call.Messages = messages
+	}
+	return call, true
+}
+
+// requests returns the captured request messages, at least one
+func (c *CapturedCall) requests() []interface{} {
+	if len(c.Messages) == 0 {
+		payload := c.Payload
+		if payload == nil {
+			payload = []byte{}
+		}
+		return []interface{}{payload}
+	}
+	requests := make([]interface{}, len(c.Messages))
+	for i, message := range c.Messages {
+		requests[i] = message
+	}
+	return requests
+}
+
+// parseHAR extracts the gRPC-family requests of a HAR archive
+func parseHAR(data []byte) ([]CapturedCall, error) {
+	var har struct {
+		Log struct {
+			Entries []struct {
+				Request struct {
+					Method  string `json:"method"`
+					URL     string `json:"url"`
+					Headers []struct {
+						Name  string `json:"name"`
+						Value string `json:"value"`
+					} `json:"headers"`
+					PostData *struct {
+						MimeType string `json:"mimeType"`
+						Text     string `json:"text"`
+						Encoding string `json:"encoding"`
+					} `json:"postData"`
+				} `json:"request"`
+			} `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("failed to parse HAR: %v", err)
+	}
+
+	var calls []CapturedCall
+	for _, entry := range har.Log.Entries {
+		req := entry.Request
+		if req.Method != "POST" {
+			continue
+		}
+		u, err := url.Parse(req.URL)
+		if err != nil {
+			continue
+		}
+
+		headers := make(map[string][]string)
+		for _, h := range req.Headers {
+			key := strings.ToLower(h.Name)
+			headers[key] = append(headers[key], h.Value)
+		}
+		contentType := ""
+		if values := headers["content-type"]; len(values) > 0 {
+			contentType = values[0]
+		}
+
+		var body []byte
+		if pd := req.PostData; pd != nil {
+			if contentType == "" {
+				contentType = pd.MimeType
+			}
+			body = []byte(pd.Text)
+			if pd.Encoding == "base64" {
+				if decoded, err := base64.StdEncoding.DecodeString(pd.Text); err == nil {
+					body = decoded
+				}
+			}
+		}
+
+		if call, ok := newCapturedCall(u.Path, contentType, headers, body, "har"); ok {
+			calls = append(calls, *call)
+		}
+	}
+	return calls, nil
+}
+
+// Link-layer types of the captures parsePcap understands
+const (
+	linkNull     = 0
+	linkEthernet = 1
+	linkRaw      = 101
+	linkLoop     = 108
+	linkSLL      = 113
+	linkIPv4     = 228
+	linkIPv6     = 229
+	linkSLL2     = 276
+)
+
+// capturedPacket is one link-layer frame of a capture
+type capturedPacket struct {
+	linkType uint32
+	data     []byte
+}
+
+// tcpFlow identifies one direction of a TCP connection
+type tcpFlow struct {
+	src, dst string
+}
+
+type tcpSegment struct {
+	seq     uint32
+	payload []byte
+}
+
+// parsePcap extracts gRPC requests from a pcap or pcapng capture of
+// plaintext HTTP/2 (h2c). Each client-to-server TCP stream that starts with
+// the HTTP/2 preface is reassembled and its HEADERS frames decoded with HPACK.
+func parsePcap(data []byte) ([]CapturedCall, error) {
+	packets, err := readCapture(data)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make(map[tcpFlow][]tcpSegment)
+	var flows []tcpFlow
+	for _, pkt := range packets {
+		flow, seg, ok := decodeTCP(pkt)
+		if !ok || len(seg.payload) == 0 {
+			continue
+		}
+		if _, seen := segments[flow]; !seen {
+			flows = append(flows, flow)
+		}
+		segments[flow] = append(segments[flow], seg)
+	}
+
+	var calls []CapturedCall
+	for _, flow := range flows {
+		stream := reassembleTCP(segments[flow])
+		if bytes.HasPrefix(stream, []byte(http2.ClientPreface)) {
+			calls = append(calls, http2Calls(stream[len(http2.ClientPreface):])...)
+		}
+	}
+	return calls, nil
+}
+
+// readCapture splits a pcap or pcapng file into link-layer frames
+func readCapture(data []byte) ([]capturedPacket, error) {
+	if len(data) < 24 {
+		return nil, fmt.Errorf("capture file too short")
+	}
+
+	var order binary.ByteOrder
+	switch binary.LittleEndian.Uint32(data) {
+	case 0xA1B2C3D4, 0xA1B23C4D:
+		order = binary.LittleEndian
+	case 0xD4C3B2A1, 0x4D3CB2A1:
+		order = binary.BigEndian
+	case 0x0A0D0D0A:
+		return readPcapNG(data)
+	default:
+		return nil, fmt.Errorf("not a pcap or pcapng file")
+	}
+
+	linkType := order.Uint32(data[20:]) & 0xFFFF
+	var packets []capturedPacket
+	for off := 24; off+16 <= len(data); {
+		capLen := int(order.Uint32(data[off+8:]))
+		off += 16
+		if capLen > len(data)-off {
+			break
+		}
+		packets = append(packets, capturedPacket{linkType: linkType, data: data[off : off+capLen]})
+		off += capLen
+	}
+	return packets, nil
+}
+
+// readPcapNG walks pcapng blocks, keeping the link type of every interface
+// and the frames of enhanced and simple packet blocks
+func readPcapNG(data []byte) ([]capturedPacket, error) {
+	var (
+		order      binary.ByteOrder = binary.LittleEndian
+		interfaces []uint32
+		packets    []capturedPacket
+	)
+	for off := 0; off+12 <= len(data); {
+		blockType := order.Uint32(data[off:])
+		if blockType == 0x0A0D0D0A {
+			// The section header's byte-order magic decides the endianness
+			// of every block that follows
+			if binary.LittleEndian.Uint32(data[off+8:]) == 0x1A2B3C4D {
+				order = binary.LittleEndian
+			} else {
+				order = binary.BigEndian
+			}
+			interfaces = nil
+		}
+		blockLen := int(order.Uint32(data[off+4:]))
+		if blockLen < 12 || blockLen > len(data)-off {
+			break
+		}
+		body := data[off+8 : off+blockLen-4]
+
+		switch blockType {
+		case 1: // Interface Description Block
+			if len(body) >= 2 {
+				interfaces = append(interfaces, uint32(order.Uint16(body)))
+			}
+		case 6: // Enhanced Packet Block
+			if len(body) >= 20 {
+				iface, capLen := int(order.Uint32(body)), int(order.Uint32(body[12:]))
+				if iface < len(interfaces) && 20+capLen <= len(body) {
+					packets = append(packets, capturedPacket{linkType: interfaces[iface], data: body[20 : 20+capLen]})
+				}
+			}
+		case 3: // Simple Packet Block
+			if len(body) >= 4 && len(interfaces) > 0 {
+				capLen := int(order.Uint32(body))
+				if capLen > len(body)-4 {
+					capLen = len(body) - 4
+				}
+				packets = append(packets, capturedPacket{linkType: interfaces[0], data: body[4 : 4+capLen]})
+			}
+		}
+		off += blockLen
+	}
+	if packets == nil && interfaces == nil {
+		return nil, fmt.Errorf("not a pcapng file")
+	}
+	return packets, nil
+}
+
+// decodeTCP strips the link, IP and TCP headers of a frame
+func decodeTCP(pkt capturedPacket) (tcpFlow, tcpSegment, bool) {
+	data := pkt.data
+	switch pkt.linkType {
+	case linkEthernet:
+		if len(data) < 14 {
+			return tcpFlow{}, tcpSegment{}, false
+		}
+		etherType, off := binary.BigEndian.Uint16(data[12:]), 14
+		for (etherType == 0x8100 || etherType == 0x88A8) && len(data) >= off+4 {
+			etherType, off = binary.BigEndian.Uint16(data[off+2:]), off+4
+		}
+		data = data[off:]
+	case linkNull, linkLoop:
+		if len(data) < 4 {
+			return tcpFlow{}, tcpSegment{}, false
+		}
+		data = data[4:]
+	case linkSLL:
+		if len(data) < 16 {
+			return tcpFlow{}, tcpSegment{}, false
+		}
+		data = data[16:]
+	case linkSLL2:
+		if len(data) < 20 {
+			return tcpFlow{}, tcpSegment{}, false
+		}
+		data = data[20:]
+	case linkRaw, linkIPv4, linkIPv6:
+	default:
+		return tcpFlow{}, tcpSegment{}, false
+	}
+	if len(data) < 1 {
+		return tcpFlow{}, tcpSegment{}, false
+	}
+
+	var src, dst string
+	var tcp []byte
+	switch data[0] >> 4 {
+	case 4:
+		ihl := int(data[0]&0x0F) * 4
+		if len(data) < 20 || ihl < 20 || len(data) < ihl || data[9] != 6 {
+			return tcpFlow{}, tcpSegment{}, false
+		}
+		total := int(binary.BigEndian.Uint16(data[2:]))
+		if total < ihl || total > len(data) {
+			total = len(data)
+		}
+		src, dst = fmt.Sprintf("%d.%d.%d.%d", data[12], data[13], data[14], data[15]), fmt.Sprintf("%d.%d.%d.%d", data[16], data[17], data[18], data[19])
+		tcp = data[ihl:total]
+	case 6:
+		// Extension headers are not followed; TCP is expected right after
+		if len(data) < 40 || data[6] != 6 {
+			return tcpFlow{}, tcpSegment{}, false
+		}
+		src, dst = fmt.Sprintf("[%x]", data[8:24]), fmt.Sprintf("[%x]", data[24:40])
+		end := 40 + int(binary.BigEndian.Uint16(data[4:]))
+		if end > len(data) {
+			end = len(data)
+		}
+		tcp = data[40:end]
+	default:
+		return tcpFlow{}, tcpSegment{}, false
+	}
+
+	if len(tcp) < 20 {
+		return tcpFlow{}, tcpSegment{}, false
+	}
+	dataOff := int(tcp[12]>>4) * 4
+	if dataOff < 20 || dataOff > len(tcp) {
+		return tcpFlow{}, tcpSegment{}, false
+	}
+	flow := tcpFlow{
+		src: fmt.Sprintf("%s:%d", src, binary.BigEndian.Uint16(tcp[0:])),
+		dst: fmt.Sprintf("%s:%d", dst, binary.BigEndian.Uint16(tcp[2:])),
+	}
+	return flow, tcpSegment{seq: binary.BigEndian.Uint32(tcp[4:]), payload: tcp[dataOff:]}, true
+}
+
+// reassembleTCP orders the segments of one direction by sequence number,
+// dropping retransmitted bytes and stopping at the first gap
+func reassembleTCP(segments []tcpSegment) []byte {
+	base := segments[0].seq
+	for _, seg := range segments {
+		if int32(seg.seq-base) < 0 {
+			base = seg.seq
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].seq-base < segments[j].seq-base
+	})
+
+	var stream []byte
+	next := uint32(0)
+	for _, seg := range segments {
+		rel := seg.seq - base
+		end := rel + uint32(len(seg.payload))
+		if rel > next {
+			break
+		}
+		if end > next {
+			stream = append(stream, seg.payload[next-rel:]...)
+			next = end
+		}
+	}
+	return stream
+}
+
+// http2Calls reads the client side of an HTTP/2 connection and returns the
+// gRPC requests on it
+func http2Calls(stream []byte) []CapturedCall {
+	framer := http2.NewFramer(io.Discard, bytes.NewReader(stream))
+	framer.SetMaxReadFrameSize(1 << 24)
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+
+	type request struct {
+		path, contentType string
+		headers           map[string][]string
+		body              []byte
+	}
+	requests := make(map[uint32]*request)
+	var order []uint32
+
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			break
+		}
+		switch f := frame.(type) {
+		case *http2.MetaHeadersFrame:
+			if _, seen := requests[f.StreamID]; seen {
+				continue // client trailers
+			}
+			req := &request{headers: make(map[string][]string)}
+			for _, field := range f.Fields {
+				switch field.Name {
+				case ":path":
+					req.path = field.Value
+				case "content-type":
+					req.contentType = field.Value
+				}
+				req.headers[field.Name] = append(req.headers[field.Name], field.Value)
+			}
+			requests[f.StreamID] = req
+			order = append(order, f.StreamID)
+		case *http2.DataFrame:
+			if req, ok := requests[f.StreamID]; ok {
+				req.body = append(req.body, f.Data()...)
+			}
+		}
+	}
+
+	var calls []CapturedCall
+	for _, id := range order {
+		req := requests[id]
+		path := req.path
+		if pos := strings.IndexByte(path, '?'); pos >= 0 {
+			path = path[:pos]
+		}
+		if call, ok := newCapturedCall(path, req.contentType, req.headers, req.body, "pcap"); ok {
+			calls = append(calls, *call)
+		}
+	}
+	return calls
+}
+
+// saveCapturedCalls writes captured requests for later -replay
+func saveCapturedCalls(filename string, calls []CapturedCall) error {
+	data, err := json.MarshalIndent(calls, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal captured calls: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to save captured calls: %v", err)
+	}
+	return nil
+}
+
+// loadCapturedCalls reads a file written by saveCapturedCalls
+func loadCapturedCalls(filename string) ([]CapturedCall, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read captured calls: %v", err)
+	}
+	var calls []CapturedCall
+	if err := json.Unmarshal(data, &calls); err != nil {
+		return nil, fmt.Errorf("failed to parse captured calls: %v", err)
+	}
+	for i, call := range calls {
+		service, method := splitFullMethod(call.Method)
+		if !grpcFullNamePattern.MatchString(service) || !grpcIdentPattern.MatchString(method) {
+			return nil, fmt.Errorf("captured call %d: invalid method %q (want pkg.Service/Method)", i+1, call.Method)
+		}
+	}
+	return calls, nil
+}
+
+// findCapturedCall returns the first captured request for service/method.
+// A short service name matches a captured package-qualified one.
+func findCapturedCall(calls []CapturedCall, service, method string) (*CapturedCall, int) {
+	var found *CapturedCall
+	count := 0
+	for i := range calls {
+		capturedService, capturedMethod := splitFullMethod(calls[i].Method)
+		if capturedMethod != method {
+			continue
+		}
+		if capturedService != service && !strings.HasSuffix(capturedService, "."+service) {
+			continue
+		}
+		if found == nil {
+			found = &calls[i]
+		}
+		count++
+	}
+	return found, count
+}
+
+// rawJSONCodec sends pre-encoded JSON captured from Connect or gRPC-Web JSON
+// clients and discards the reply
+type rawJSONCodec struct{ rawCodec }
+
+func (rawJSONCodec) Name() string {
+	return "json"
+}
+
+// replayCapturedCall sends the captured request messages with their metadata.
+// Like invokeEmpty it uses a stream, so captures of server, client and bidi
+// streaming calls replay too; Connect unary procedures get a unary POST.
+func replayCapturedCall(ctx context.Context, conn grpc.ClientConnInterface, fullMethod string, call *CapturedCall, opts ...grpc.CallOption) error {
+	md := metadata.MD{}
+	for key, values := range call.Metadata {
+		for _, value := range values {
+			// Binary headers travel base64 encoded; grpc-go encodes them itself
+			if strings.HasSuffix(key, "-bin") {
+				if decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(value, "=")); err == nil {
+					value = string(decoded)
+				}
+			}
+			md.Append(key, value)
+		}
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	var codec encoding.Codec = rawCodec{}
+	if call.Codec == "json" {
+		codec = rawJSONCodec{}
+	}
+	opts = append(opts, grpc.ForceCodec(codec))
+	requests := call.requests()
+	if connect, ok := conn.(*connectConn); ok && len(requests) == 1 {
+		if refused, err := connect.unary(ctx, fullMethod, requests[0], nil, opts...); !refused {
+			return err
+		}
+	}
+	return invokeStream(ctx, conn, fullMethod, requests, opts...)
+}
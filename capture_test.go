@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// grpcFrames wraps messages in uncompressed length-prefixed gRPC frames
+func grpcFrames(messages ...string) []byte {
+	var body []byte
+	for _, message := range messages {
+		prefix := make([]byte, 5)
+		binary.BigEndian.PutUint32(prefix[1:], uint32(len(message)))
+		body = append(append(body, prefix...), message...)
+	}
+	return body
+}
+
+type harRequest struct {
+	method, url, mimeType, text, encoding string
+	headers                               map[string]string
+}
+
+func harArchive(t *testing.T, requests ...harRequest) []byte {
+	t.Helper()
+	type header struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	var entries []interface{}
+	for _, req := range requests {
+		headers := []header{}
+		for name, value := range req.headers {
+			headers = append(headers, header{name, value})
+		}
+		entry := map[string]interface{}{"method": req.method, "url": req.url, "headers": headers}
+		if req.text != "" || req.mimeType != "" {
+			entry["postData"] = map[string]string{"mimeType": req.mimeType, "text": req.text, "encoding": req.encoding}
+		}
+		entries = append(entries, map[string]interface{}{"request": entry})
+	}
+	data, err := json.Marshal(map[string]interface{}{"log": map[string]interface{}{"entries": entries}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseHAR(t *testing.T) {
+	b64 := func(data []byte) string { return base64.StdEncoding.EncodeToString(data) }
+	tests := []struct {
+		name    string
+		request harRequest
+		want    []CapturedCall
+	}{
+		{
+			name: "gRPC-Web with metadata",
+			request: harRequest{
+				method: "POST", url: "https://api.example.com/acme.v1.UserService/GetUser",
+				headers:  map[string]string{"Content-Type": "application/grpc-web+proto", "Authorization": "Bearer abc", "X-Grpc-Web": "1", "User-Agent": "browser"},
+				mimeType: "application/grpc-web+proto", text: b64(grpcFrames("\x0a\x01x")), encoding: "base64",
+			},
+			want: []CapturedCall{{
+				Method: "acme.v1.UserService/GetUser", ContentType: "application/grpc-web+proto", Codec: "proto",
+				Metadata: map[string][]string{"authorization": {"Bearer abc"}}, Payload: []byte("\x0a\x01x"), Source: "har",
+			}},
+		},
+		{
+			name: "gRPC-Web text body",
+			request: harRequest{
+				method: "POST", url: "https://api.example.com/prefix/UserService/GetUser",
+				mimeType: "application/grpc-web-text", text: b64(grpcFrames("\x0a\x01y")),
+			},
+			want: []CapturedCall{{
+				Method: "UserService/GetUser", ContentType: "application/grpc-web-text", Codec: "proto",
+				Metadata: map[string][]string{}, Payload: []byte("\x0a\x01y"), Source: "har",
+			}},
+		},
+		{
+			name: "Connect unary JSON",
+			request: harRequest{
+				method: "POST", url: "https://api.example.com/acme.v1.UserService/GetUser?x=1",
+				headers:  map[string]string{"Content-Type": "application/json", "Connect-Protocol-Version": "1"},
+				mimeType: "application/json", text: `{"id":"1"}`,
+			},
+			want: []CapturedCall{{
+				Method: "acme.v1.UserService/GetUser", ContentType: "application/json", Codec: "json",
+				Metadata: map[string][]string{}, Payload: []byte(`{"id":"1"}`), Source: "har",
+			}},
+		},
+		{
+			name: "client stream keeps every message",
+			request: harRequest{
+				method: "POST", url: "https://api.example.com/acme.v1.ChatService/Send",
+				mimeType: "application/connect+json", text: string(grpcFrames(`{"a":1}`, `{"a":2}`)),
+			},
+			want: []CapturedCall{{
+				Method: "acme.v1.ChatService/Send", ContentType: "application/connect+json", Codec: "json",
+				Metadata: map[string][]string{}, Payload: []byte(`{"a":1}`),
+				Messages: [][]byte{[]byte(`{"a":1}`), []byte(`{"a":2}`)}, Source: "har",
+			}},
+		},
+		{
+			name: "plain JSON without Connect header",
+			request: harRequest{
+				method: "POST", url: "https://api.example.com/acme.v1.UserService/GetUser",
+				mimeType: "application/json", text: `{}`,
+			},
+		},
+		{
+			name: "GET request",
+			request: harRequest{
+				method: "GET", url: "https://api.example.com/acme.v1.UserService/GetUser",
+				headers: map[string]string{"Content-Type": "application/grpc-web+proto"},
+			},
+		},
+		{
+			name: "path that is not a method",
+			request: harRequest{
+				method: "POST", url: "https://api.example.com/v1/users/",
+				mimeType: "application/grpc-web+proto", text: b64(grpcFrames("")), encoding: "base64",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, err := parseHAR(harArchive(t, tt.request))
+			if err != nil {
+				t.Fatalf("parseHAR: %v", err)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("parseHAR = %+v, want %+v", calls, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseHAR([]byte("not json")); err == nil {
+		t.Error("parseHAR accepted an invalid archive")
+	}
+}
+
+type h2Request struct {
+	path, contentType string
+	headers           map[string]string
+	body              []byte
+}
+
+// h2cClientStream encodes the client side of an h2c connection carrying the
+// requests, one stream each
+func h2cClientStream(t *testing.T, requests ...h2Request) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString(http2.ClientPreface)
+	framer := http2.NewFramer(&buf, nil)
+	if err := framer.WriteSettings(); err != nil {
+		t.Fatal(err)
+	}
+
+	var block bytes.Buffer
+	encoder := hpack.NewEncoder(&block)
+	for i, req := range requests {
+		block.Reset()
+		fields := []hpack.HeaderField{
+			{Name: ":method", Value: "POST"},
+			{Name: ":scheme", Value: "http"},
+			{Name: ":path", Value: req.path},
+			{Name: "content-type", Value: req.contentType},
+		}
+		names := make([]string, 0, len(req.headers))
+		for name := range req.headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fields = append(fields, hpack.HeaderField{Name: name, Value: req.headers[name]})
+		}
+		for _, field := range fields {
+			if err := encoder.WriteField(field); err != nil {
+				t.Fatal(err)
+			}
+		}
+		streamID := uint32(2*i + 1)
+		if err := framer.WriteHeaders(http2.HeadersFrameParam{StreamID: streamID, BlockFragment: block.Bytes(), EndHeaders: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := framer.WriteData(streamID, true, req.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// tcpSegmentFrame builds an Ethernet/IPv4/TCP frame from 10.0.0.1:50000 to
+// 10.0.0.2:8080, or the reverse direction
+func tcpSegmentFrame(seq uint32, payload []byte, reverse bool) []byte {
+	src, dst := []byte{10, 0, 0, 1}, []byte{10, 0, 0, 2}
+	srcPort, dstPort := uint16(50000), uint16(8080)
+	if reverse {
+		src, dst, srcPort, dstPort = dst, src, dstPort, srcPort
+	}
+
+	ip := make([]byte, 20)
+	ip[0], ip[9] = 0x45, 6
+	binary.BigEndian.PutUint16(ip[2:], uint16(40+len(payload)))
+	copy(ip[12:], src)
+	copy(ip[16:], dst)
+
+	tcp := make([]byte, 20)
+	binary.BigEndian.PutUint16(tcp[0:], srcPort)
+	binary.BigEndian.PutUint16(tcp[2:], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	tcp[12] = 5 << 4
+
+	ethernet := make([]byte, 14)
+	binary.BigEndian.PutUint16(ethernet[12:], 0x0800)
+	return append(append(append(ethernet, ip...), tcp...), payload...)
+}
+
+// pcapFile writes frames as a little-endian pcap
+func pcapFile(frames [][]byte) []byte {
+	le := binary.LittleEndian
+	header := make([]byte, 24)
+	le.PutUint32(header[0:], 0xA1B2C3D4)
+	le.PutUint16(header[4:], 2)
+	le.PutUint16(header[6:], 4)
+	le.PutUint32(header[16:], 65535)
+	le.PutUint32(header[20:], linkEthernet)
+	data := header
+	for _, frame := range frames {
+		record := make([]byte, 16)
+		le.PutUint32(record[8:], uint32(len(frame)))
+		le.PutUint32(record[12:], uint32(len(frame)))
+		data = append(append(data, record...), frame...)
+	}
+	return data
+}
+
+// pcapngFile writes frames as a pcapng section in the given byte order
+func pcapngFile(order interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}, frames [][]byte) []byte {
+	block := func(blockType uint32, body []byte) []byte {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		b := make([]byte, 8, 12+len(body))
+		order.PutUint32(b[0:], blockType)
+		order.PutUint32(b[4:], uint32(12+len(body)))
+		b = append(b, body...)
+		return order.AppendUint32(b, uint32(12+len(body)))
+	}
+
+	shb := order.AppendUint32(nil, 0x1A2B3C4D)
+	shb = order.AppendUint16(shb, 1)
+	shb = order.AppendUint16(shb, 0)
+	shb = append(shb, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	idb := order.AppendUint16(nil, linkEthernet)
+	idb = order.AppendUint16(idb, 0)
+	idb = order.AppendUint32(idb, 65535)
+
+	data := append(block(0x0A0D0D0A, shb), block(1, idb)...)
+	for _, frame := range frames {
+		epb := make([]byte, 20)
+		order.PutUint32(epb[12:], uint32(len(frame)))
+		order.PutUint32(epb[16:], uint32(len(frame)))
+		data = append(data, block(6, append(epb, frame...))...)
+	}
+	return data
+}
+
+func TestParsePcap(t *testing.T) {
+	getUser := h2Request{
+		path: "/acme.v1.UserService/GetUser", contentType: "application/grpc",
+		headers: map[string]string{"authorization": "Bearer abc", "te": "trailers"},
+		body:    grpcFrames("\x0a\x01x"),
+	}
+	stream := h2cClientStream(t,
+		getUser,
+		h2Request{path: "/acme.v1.ChatService/Send", contentType: "application/grpc+json", body: grpcFrames(`{"a":1}`, `{"a":2}`)},
+		h2Request{path: "/index.html", contentType: "text/html"},
+	)
+	want := []CapturedCall{
+		{
+			Method: "acme.v1.UserService/GetUser", ContentType: "application/grpc", Codec: "proto",
+			Metadata: map[string][]string{"authorization": {"Bearer abc"}}, Payload: []byte("\x0a\x01x"), Source: "pcap",
+		},
+		{
+			Method: "acme.v1.ChatService/Send", ContentType: "application/grpc+json", Codec: "json",
+			Metadata: map[string][]string{}, Payload: []byte(`{"a":1}`),
+			Messages: [][]byte{[]byte(`{"a":1}`), []byte(`{"a":2}`)}, Source: "pcap",
+		},
+	}
+
+	// The stream is split into three segments, the first ending after the
+	// GetUser request, with sequence numbers that wrap around
+	const isn = 0xFFFFFF00
+	cut1, cut2 := len(h2cClientStream(t, getUser)), len(stream)-10
+	first := tcpSegmentFrame(isn, stream[:cut1], false)
+	second := tcpSegmentFrame(isn+uint32(cut1), stream[cut1:cut2], false)
+	third := tcpSegmentFrame(isn+uint32(cut2), stream[cut2:], false)
+	response := tcpSegmentFrame(1, []byte("\x00\x00\x00\x04\x00\x00\x00\x00\x00"), true)
+
+	tests := []struct {
+		name string
+		data []byte
+		want []CapturedCall
+	}{
+		{"pcap in order", pcapFile([][]byte{first, response, second, third}), want},
+		{"pcapng little-endian", pcapngFile(binary.LittleEndian, [][]byte{first, second, third}), want},
+		{"pcapng big-endian", pcapngFile(binary.BigEndian, [][]byte{first, second, third}), want},
+		{"out of order with retransmission", pcapFile([][]byte{third, first, second, first, second}), want},
+		{"gap drops the rest of the stream", pcapFile([][]byte{first, third}), want[:1]},
+		{"server side only", pcapFile([][]byte{response}), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls, err := parsePcap(tt.data)
+			if err != nil {
+				t.Fatalf("parsePcap: %v", err)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("parsePcap = %+v, want %+v", calls, tt.want)
+			}
+		})
+	}
+
+	if _, err := parsePcap([]byte("definitely not a capture file")); err == nil {
+		t.Error("parsePcap accepted a file that is not a capture")
+	}
+}
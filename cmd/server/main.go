@@ -11,7 +11,9 @@ import (
 	"time"
 
 	pb "github.com/user/grpc-scanner/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	channelzsvc "google.golang.org/grpc/channelz/service"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
@@ -354,10 +356,25 @@ func (s *authService) CreateToken(ctx context.Context, req *pb.CreateTokenReques
 	
 	// Simple validation (in real app, check password)
 	if req.GetUsername() == "" || req.GetPassword() == "" {
-		return &pb.CreateTokenResponse{
-			Success: false,
-			Error:   "Username and password required",
-		}, status.Error(codes.InvalidArgument, "missing credentials")
+		// Report each missing field, like APIs using google.rpc.BadRequest do
+		badRequest := &errdetails.BadRequest{}
+		if req.GetUsername() == "" {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       "username",
+				Description: "username is required",
+			})
+		}
+		if req.GetPassword() == "" {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       "password",
+				Description: "password is required",
+			})
+		}
+		st, err := status.New(codes.InvalidArgument, "missing credentials").WithDetails(badRequest)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "missing credentials")
+		}
+		return nil, st.Err()
 	}
 	
 	// Generate token
@@ -507,6 +524,7 @@ func main() {
 	port := flag.Int("port", 50051, "The server port")
 	enableHealth := flag.Bool("health", true, "Enable health service")
 	enableReflection := flag.Bool("reflection", false, "Enable reflection service")
+	enableChannelz := flag.Bool("channelz", false, "Enable channelz admin service")
 	enableAuth := flag.Bool("auth", true, "Enable authentication")
 	flag.Parse()
 
@@ -557,6 +575,12 @@ func main() {
 		log.Println("Reflection service registered")
 	}
 
+	// Register channelz service if enabled
+	if *enableChannelz {
+		channelzsvc.RegisterChannelzServiceToServer(s)
+		log.Println("Channelz service registered")
+	}
+
 	log.Printf("Server listening on :%d", *port)
 	log.Println("Available services:")
 	log.Println("  - HelloService (no auth required)")
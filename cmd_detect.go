@@ -10,17 +10,14 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
-
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/status"
 )
 
 // DetectResult represents the result of checking a single target
 type DetectResult struct {
 	Target    string
 	IsGRPC    bool
+	Transport string
+	Endpoint  *EndpointInfo
 	Error     string
 	Latency   time.Duration
 	Timestamp time.Time
@@ -38,6 +35,8 @@ func runDetectCommand(args []string) {
 		fmt.Println("  -timeout int      Timeout per target in seconds (default: 3)")
 		fmt.Println("  -output string    Output file for results (default: stdout)")
 		fmt.Println("  -json             Output results in JSON format")
+		fmt.Println("  -transport string Transport: auto, native, grpc-web, grpc-web-text, connect or connect-json (default: auto)")
+		fmt.Println("  -tls              Connect with TLS")
 		fmt.Println("  -v                Verbose output")
 		fmt.Println("\nExamples:")
 		fmt.Println("  grpc-scanner detect -targets=domains.txt -threads=100")
@@ -54,6 +53,8 @@ func runDetectCommand(args []string) {
 		outputFile  = ""
 		jsonOutput  = false
 		verbose     = false
+		transport   = transportAuto
+		useTLS      = false
 	)
 
 	// Parse detect-specific flags
@@ -73,6 +74,10 @@ func runDetectCommand(args []string) {
 			jsonOutput = true
 		} else if arg == "-v" {
 			verbose = true
+		} else if strings.HasPrefix(arg, "-transport=") {
+			transport = strings.TrimPrefix(arg, "-transport=")
+		} else if arg == "-tls" {
+			useTLS = true
 		}
 	}
 
@@ -124,6 +129,10 @@ func runDetectCommand(args []string) {
 		log.Fatal("No targets provided. Use -target, -targets, or provide input via stdin")
 	}
 
+	if !validTransport(transport) {
+		log.Fatalf("Invalid -transport %q (use one of: %s)", transport, strings.Join(transportNames, ", "))
+	}
+
 	// Setup output
 	var output *os.File
 	if outputFile != "" {
@@ -140,7 +149,7 @@ func runDetectCommand(args []string) {
 	// Start detection
 	fmt.Fprintf(os.Stderr, "[*] Starting gRPC detection on %d targets with %d threads\n", len(targets), threads)
 	
-	results := detectGRPCServices(targets, threads, time.Duration(timeout)*time.Second, verbose)
+	results := detectGRPCServices(targets, threads, time.Duration(timeout)*time.Second, verbose, transport, useTLS)
 	
 	// Output results
 	grpcCount := 0
@@ -155,6 +164,18 @@ func runDetectCommand(args []string) {
 			fmt.Fprintf(output, "  {")
 			fmt.Fprintf(output, `"target":"%s",`, result.Target)
 			fmt.Fprintf(output, `"is_grpc":%v,`, result.IsGRPC)
+			if result.Transport != "" {
+				fmt.Fprintf(output, `"transport":"%s",`, result.Transport)
+			}
+			if result.Endpoint != nil {
+				fmt.Fprintf(output, `"class":"%s",`, result.Endpoint.Class)
+				if result.Endpoint.Server != "" {
+					fmt.Fprintf(output, `"server":"%s",`, strings.ReplaceAll(result.Endpoint.Server, `"`, `\"`))
+				}
+				if result.Endpoint.StatusLine != "" {
+					fmt.Fprintf(output, `"status_line":"%s",`, strings.ReplaceAll(result.Endpoint.StatusLine, `"`, `\"`))
+				}
+			}
 			fmt.Fprintf(output, `"latency_ms":%d,`, result.Latency.Milliseconds())
 			if result.Error != "" {
 				fmt.Fprintf(output, `"error":"%s",`, strings.ReplaceAll(result.Error, `"`, `\"`))
@@ -173,10 +194,17 @@ func runDetectCommand(args []string) {
 		for _, result := range results {
 			if result.IsGRPC {
 				grpcCount++
-				fmt.Fprintf(output, "[+] %s - gRPC service detected (%dms)\n", 
-					result.Target, result.Latency.Milliseconds())
+				if result.Transport != "" && result.Transport != transportNative {
+					fmt.Fprintf(output, "[+] %s - gRPC service detected via %s (%dms)\n",
+						result.Target, result.Transport, result.Latency.Milliseconds())
+				} else {
+					fmt.Fprintf(output, "[+] %s - gRPC service detected (%dms)\n", 
+						result.Target, result.Latency.Milliseconds())
+				}
 			} else if verbose {
-				if result.Error != "" {
+				if result.Endpoint != nil {
+					fmt.Fprintf(output, "[-] %s - Not gRPC: %s\n", result.Target, result.Endpoint.Describe())
+				} else if result.Error != "" {
 					fmt.Fprintf(output, "[-] %s - Not gRPC: %s\n", result.Target, result.Error)
 				} else {
 					fmt.Fprintf(output, "[-] %s - Not gRPC\n", result.Target)
@@ -193,7 +221,7 @@ func runDetectCommand(args []string) {
 }
 
 // detectGRPCServices checks multiple targets concurrently
-func detectGRPCServices(targets []string, threads int, timeout time.Duration, verbose bool) []DetectResult {
+func detectGRPCServices(targets []string, threads int, timeout time.Duration, verbose bool, transport string, useTLS bool) []DetectResult {
 	var (
 		wg          sync.WaitGroup
 		resultsChan = make(chan DetectResult, len(targets))
@@ -230,7 +258,7 @@ func detectGRPCServices(targets []string, threads int, timeout time.Duration, ve
 			defer wg.Done()
 			defer func() { <-semaphore }()
 			
-			result := checkGRPCService(t, timeout)
+			result := checkGRPCService(t, timeout, transport, useTLS)
 			resultsChan <- result
 			
 			atomic.AddInt32(&processed, 1)
@@ -258,8 +286,9 @@ func detectGRPCServices(targets []string, threads int, timeout time.Duration, ve
 	return results
 }
 
-// checkGRPCService checks if a single target has a gRPC service
-func checkGRPCService(target string, timeout time.Duration) DetectResult {
+// checkGRPCService classifies a single target and reports whether any gRPC
+// flavor the transport allows answered
+func checkGRPCService(target string, timeout time.Duration, transport string, useTLS bool) DetectResult {
 	result := DetectResult{
 		Target:    target,
 		Timestamp: time.Now(),
@@ -271,53 +300,14 @@ func checkGRPCService(target string, timeout time.Duration) DetectResult {
 	}
 	
 	startTime := time.Now()
-	
-	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	
-	// Try to connect
-	conn, err := grpc.DialContext(ctx, target, 
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
-	)
-	
-	if err != nil {
-		result.Error = err.Error()
-		result.Latency = time.Since(startTime)
-		return result
-	}
-	defer conn.Close()
-	
-	// Check connection state
-	state := conn.GetState()
-	if state == connectivity.TransientFailure || state == connectivity.Shutdown {
-		result.Error = fmt.Sprintf("Connection failed: %s", state)
-		result.Latency = time.Since(startTime)
-		return result
-	}
-	
-	// Try a simple gRPC call to verify it's actually gRPC
-	// Using a non-existent method to check the error response
-	err = conn.Invoke(ctx, "/grpc.health.v1.Health/Check", nil, nil)
-	
-	if err == nil {
-		// Health check succeeded - definitely gRPC
-		result.IsGRPC = true
-		result.Latency = time.Since(startTime)
-		return result
-	}
-	
-	// Check if it's a gRPC error
-	if _, ok := status.FromError(err); ok {
-		// Got a gRPC status error - this is a gRPC service
-		result.IsGRPC = true
-		result.Latency = time.Since(startTime)
-		return result
-	}
-	
-	// Not a gRPC error - probably not a gRPC service
-	result.Error = "Non-gRPC response"
+	endpoint := classifyEndpoint(ctx, target, transport, useTLS)
+	result.Endpoint = endpoint
+	result.IsGRPC = endpoint.IsGRPC()
+	result.Transport = endpoint.Transport
+	result.Error = endpoint.Error
 	result.Latency = time.Since(startTime)
 	return result
-}
\ No newline at end of file
+}
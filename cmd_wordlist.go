@@ -2,13 +2,18 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
-	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/net/html"
 )
@@ -18,7 +23,10 @@ type WordlistExtractor struct {
 	services       map[string]bool
 	operations     map[string]bool
 	resources      map[string]bool
+	packages       map[string]bool
 	serviceMethods map[string]map[string]bool
+	calls          []CapturedCall
+	scores         map[string]*candidateScore
 }
 
 func NewWordlistExtractor() *WordlistExtractor {
@@ -26,7 +34,9 @@ func NewWordlistExtractor() *WordlistExtractor {
 		services:       make(map[string]bool),
 		operations:     make(map[string]bool),
 		resources:      make(map[string]bool),
+		packages:       make(map[string]bool),
 		serviceMethods: make(map[string]map[string]bool),
+		scores:         make(map[string]*candidateScore),
 	}
 }
 
@@ -42,22 +52,6 @@ var (
 	wlResourcePattern = regexp.MustCompile(`\b([A-Z][a-z]+(?:[A-Z][a-z]+)*)\b`)
 )
 
-func (e *WordlistExtractor) ExtractFromURL(url string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to fetch URL: %v", err)
-	}
-	defer resp.Body.Close()
-
-	doc, err := html.Parse(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to parse HTML: %v", err)
-	}
-
-	e.extractFromNode(doc)
-	return nil
-}
-
 func (e *WordlistExtractor) extractFromNode(n *html.Node) {
 	if n.Type == html.TextNode {
 		e.extractFromText(n.Data)
@@ -85,8 +79,7 @@ func (e *WordlistExtractor) extractFromText(text string) {
 		if len(match) > 2 {
 			service := strings.Title(match[1])
 			method := strings.Title(match[2])
-			e.services[service] = true
-			e.addServiceMethod(service, method)
+			e.addServiceMethod(service, method, sourceURLPath)
 		}
 	}
 
@@ -95,7 +88,7 @@ func (e *WordlistExtractor) extractFromText(text string) {
 	for _, match := range matches {
 		if len(match) > 1 {
 			service := strings.Title(match[1])
-			e.services[service] = true
+			e.addService(service, sourceURLPath)
 		}
 	}
 
@@ -107,10 +100,9 @@ func (e *WordlistExtractor) extractFromText(text string) {
 			resource := match[2]
 			e.operations[operation] = true
 			e.resources[resource] = true
-			e.services[resource] = true
 			
 			// Map method to service
-			e.addServiceMethod(resource, operation+resource)
+			e.addServiceMethod(resource, operation+resource, sourceText)
 		}
 	}
 
@@ -119,7 +111,7 @@ func (e *WordlistExtractor) extractFromText(text string) {
 	for _, match := range matches {
 		if len(match) > 1 {
 			service := match[1]
-			e.services[service] = true
+			e.addService(service, sourceText)
 		}
 	}
 }
@@ -137,7 +129,7 @@ func (e *WordlistExtractor) extractFromCode(code string) {
 			classMatch := regexp.MustCompile(`(?:class|interface)\s+([A-Z][a-zA-Z]+)`).FindStringSubmatch(line)
 			if len(classMatch) > 1 {
 				currentClass = classMatch[1]
-				e.services[currentClass] = true
+				e.addService(currentClass, sourceCodeBlock)
 			}
 		}
 
@@ -148,7 +140,7 @@ func (e *WordlistExtractor) extractFromCode(code string) {
 			if len(methodMatch) > 1 {
 				method := methodMatch[1]
 				if method != "function" && method != "func" && method != "def" {
-					e.addServiceMethod(currentClass, strings.Title(method))
+					e.addServiceMethod(currentClass, strings.Title(method), sourceCodeBlock)
 				}
 			}
 		}
@@ -166,20 +158,229 @@ func (e *WordlistExtractor) extractFromCode(code string) {
 }
 
 // Helper method to add service-method mapping
-func (e *WordlistExtractor) addServiceMethod(service, method string) {
+func (e *WordlistExtractor) addServiceMethod(service, method, source string) {
+	e.addService(service, source)
 	if e.serviceMethods[service] == nil {
 		e.serviceMethods[service] = make(map[string]bool)
 	}
 	e.serviceMethods[service][method] = true
 }
 
+// ExtractFromOpenAPI imports the operations of an OpenAPI 2/3 document as
+// services and methods
+func (e *WordlistExtractor) ExtractFromOpenAPI(data []byte) error {
+	spec, err := parseOpenAPI(data)
+	if err != nil {
+		return err
+	}
+	for _, op := range openAPIOperations(spec) {
+		e.addServiceMethod(op.Service, op.Method, sourceOperationID)
+	}
+	return nil
+}
+
+// ExtractFromProto imports the services declared in .proto source under
+// their fully qualified names
+func (e *WordlistExtractor) ExtractFromProto(src string) {
+	_, services := parseProtoFile(src)
+	e.addProtoServices(services)
+}
+
+// ExtractFromDescriptorSet imports the services of a binary FileDescriptorSet
+func (e *WordlistExtractor) ExtractFromDescriptorSet(data []byte) error {
+	services, err := parseDescriptorSet(data)
+	if err != nil {
+		return err
+	}
+	e.addProtoServices(services)
+	return nil
+}
+
+func (e *WordlistExtractor) addProtoServices(services []protoService) {
+	for _, service := range services {
+		name := service.FullName()
+		e.addService(name, sourceStub)
+		for _, method := range service.Methods {
+			e.addServiceMethod(name, method, sourceStub)
+		}
+		if service.Package != "" {
+			e.packages[service.Package] = true
+		}
+	}
+}
+
+// ExtractFromJS imports the exact method paths gRPC-Web and Connect client
+// stubs embed in a JavaScript bundle
+func (e *WordlistExtractor) ExtractFromJS(src string) {
+	e.addProtoServices(extractJSStubs(src))
+}
+
+// ExtractFromSourceMap imports the client stubs in a source map's sources
+func (e *WordlistExtractor) ExtractFromSourceMap(data []byte) error {
+	services, err := extractSourceMapStubs(data)
+	if err != nil {
+		return err
+	}
+	e.addProtoServices(services)
+	return nil
+}
+
+// ExtractFromArchive imports the gRPC stubs compiled into an APK, AAB or IPA
+func (e *WordlistExtractor) ExtractFromArchive(filename string) error {
+	services, err := extractAPKStubs(filename)
+	if err != nil {
+		return err
+	}
+	e.addProtoServices(services)
+	return nil
+}
+
+// ExtractFromMachO imports the method paths and descriptors found in a
+// Mach-O binary
+func (e *WordlistExtractor) ExtractFromMachO(data []byte) error {
+	services, err := extractMachOStubs(data)
+	if err != nil {
+		return err
+	}
+	e.addProtoServices(services)
+	return nil
+}
+
+// ExtractFromHAR imports the gRPC-family requests recorded in a HAR archive
+func (e *WordlistExtractor) ExtractFromHAR(data []byte) error {
+	calls, err := parseHAR(data)
+	if err != nil {
+		return err
+	}
+	e.addCapturedCalls(calls)
+	return nil
+}
+
+// ExtractFromPcap imports the gRPC requests of a plaintext HTTP/2 capture
+func (e *WordlistExtractor) ExtractFromPcap(data []byte) error {
+	calls, err := parsePcap(data)
+	if err != nil {
+		return err
+	}
+	e.addCapturedCalls(calls)
+	return nil
+}
+
+func (e *WordlistExtractor) addCapturedCalls(calls []CapturedCall) {
+	for _, call := range calls {
+		pos := strings.LastIndex(call.Method, "/")
+		e.addProtoServices([]protoService{splitServiceName(call.Method[:pos], call.Method[pos+1:])})
+	}
+	e.calls = append(e.calls, calls...)
+}
+
+// ExtractFromDir imports the .proto files, descriptor sets, JavaScript
+// bundles, source maps, HAR files and packet captures below dir. JSON, YAML
+// and app packages are too common in source trees to pick up and have to be
+// passed with -input one at a time. Files that fail to parse, such as a
+// TensorFlow model saved as .pb, are logged and skipped; only walk and read
+// errors abort the import.
+func (e *WordlistExtractor) ExtractFromDir(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".proto" && ext != ".map" && ext != ".har" && !descriptorSetExts[ext] && !jsExts[ext] && !captureExts[ext] {
+			return nil
+		}
+		if err := e.ExtractFromFile(path); err != nil {
+			var pathErr *fs.PathError
+			if errors.As(err, &pathErr) {
+				return err
+			}
+			log.Printf("Skipping %s: %v", path, err)
+		}
+		return nil
+	})
+}
+
+// File extensions protoc and buf use for binary descriptor sets. ".bin" is
+// too generic to list here; ExtractFromFile sniffs those files instead.
+var descriptorSetExts = map[string]bool{".pb": true, ".desc": true, ".protoset": true, ".binpb": true}
+
+// File extensions of JavaScript bundles
+var jsExts = map[string]bool{".js": true, ".mjs": true, ".cjs": true}
+
+// File extensions of mobile app packages
+var appArchiveExts = map[string]bool{".apk": true, ".aab": true, ".ipa": true}
+
+// File extensions of packet captures
+var captureExts = map[string]bool{".pcap": true, ".pcapng": true, ".cap": true}
+
 func (e *WordlistExtractor) ExtractFromFile(filename string) error {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if appArchiveExts[ext] {
+		return e.ExtractFromArchive(filename)
+	}
+	// A .bin file is used as a descriptor set only when it parses as one,
+	// and scanned like any other file otherwise
+	if ext == ".bin" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		if e.ExtractFromDescriptorSet(data) == nil {
+			return nil
+		}
+	}
+	if ext == ".proto" || ext == ".map" || ext == ".har" || descriptorSetExts[ext] || jsExts[ext] || captureExts[ext] ||
+		ext == ".json" || ext == ".yaml" || ext == ".yml" {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		switch {
+		case ext == ".proto":
+			e.ExtractFromProto(string(data))
+			return nil
+		case descriptorSetExts[ext]:
+			return e.ExtractFromDescriptorSet(data)
+		case jsExts[ext]:
+			e.ExtractFromJS(string(data))
+			return nil
+		case ext == ".map":
+			return e.ExtractFromSourceMap(data)
+		case ext == ".har":
+			return e.ExtractFromHAR(data)
+		case captureExts[ext]:
+			return e.ExtractFromPcap(data)
+		default:
+			// Structured import when the file is an OpenAPI document, plain
+			// text scanning otherwise
+			if err := e.ExtractFromOpenAPI(data); err == nil {
+				return nil
+			}
+		}
+	}
+
 	file, err := os.Open(filename)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
+	// Mach-O executables and dylibs have no telling extension
+	magic := make([]byte, 4)
+	if n, _ := io.ReadFull(file, magic); isMachO(magic[:n]) {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
+		return e.ExtractFromMachO(data)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
@@ -190,11 +391,22 @@ func (e *WordlistExtractor) ExtractFromFile(filename string) error {
 	return scanner.Err()
 }
 
-// WriteEnhancedWordlist writes the wordlist in enhanced format with methods
-func (e *WordlistExtractor) WriteEnhancedWordlist(writer *bufio.Writer, addPatterns bool) {
+// WriteEnhancedWordlist writes the wordlist in enhanced format with methods,
+// highest scoring services first, optionally annotating each line with its
+// score
+func (e *WordlistExtractor) WriteEnhancedWordlist(writer *bufio.Writer, addPatterns, annotate bool) {
 	fmt.Fprintf(writer, "# Enhanced wordlist generated from API documentation\n")
 	fmt.Fprintf(writer, "# Format: ServiceName:method1,method2,method3\n")
-	fmt.Fprintf(writer, "# Services without methods will use default scanning\n\n")
+	fmt.Fprintf(writer, "# Services without methods will use default scanning\n")
+	fmt.Fprintf(writer, "# Sorted by confidence score, highest first\n\n")
+
+	// annotation returns the "# score=N" suffix of a service line
+	annotation := func(service string) string {
+		if !annotate {
+			return ""
+		}
+		return fmt.Sprintf(" # score=%d", e.Score(service))
+	}
 	
 	// First, write services with specific methods
 	servicesWithMethods := make([]string, 0)
@@ -203,7 +415,7 @@ func (e *WordlistExtractor) WriteEnhancedWordlist(writer *bufio.Writer, addPatte
 			servicesWithMethods = append(servicesWithMethods, service)
 		}
 	}
-	sort.Strings(servicesWithMethods)
+	e.rankServices(servicesWithMethods)
 	
 	fmt.Fprintf(writer, "# Services with extracted methods\n")
 	for _, service := range servicesWithMethods {
@@ -214,11 +426,11 @@ func (e *WordlistExtractor) WriteEnhancedWordlist(writer *bufio.Writer, addPatte
 		sort.Strings(methods)
 		
 		// Write service with methods
-		fmt.Fprintf(writer, "%s:%s\n", service, strings.Join(methods, ","))
+		fmt.Fprintf(writer, "%s:%s%s\n", service, strings.Join(methods, ","), annotation(service))
 		
 		// Add pattern variations if requested
 		if addPatterns && !strings.Contains(service, ".") && !strings.HasSuffix(service, "Service") {
-			fmt.Fprintf(writer, "%sService:%s\n", service, strings.Join(methods, ","))
+			fmt.Fprintf(writer, "%sService:%s%s\n", service, strings.Join(methods, ","), annotation(service))
 		}
 	}
 	
@@ -232,17 +444,31 @@ func (e *WordlistExtractor) WriteEnhancedWordlist(writer *bufio.Writer, addPatte
 	
 	if len(servicesWithoutMethods) > 0 {
 		fmt.Fprintf(writer, "\n# Services without specific methods (will use defaults)\n")
-		sort.Strings(servicesWithoutMethods)
+		e.rankServices(servicesWithoutMethods)
 		for _, service := range servicesWithoutMethods {
-			fmt.Fprintf(writer, "%s\n", service)
+			fmt.Fprintf(writer, "%s%s\n", service, annotation(service))
 			
 			// Add pattern variations if requested
 			if addPatterns && !strings.Contains(service, ".") && !strings.HasSuffix(service, "Service") {
-				fmt.Fprintf(writer, "%sService\n", service)
+				fmt.Fprintf(writer, "%sService%s\n", service, annotation(service))
 			}
 		}
 	}
 	
+	// Write package prefixes
+	packages := make([]string, 0, len(e.packages))
+	for pkg := range e.packages {
+		packages = append(packages, pkg)
+	}
+	sort.Strings(packages)
+
+	if len(packages) > 0 {
+		fmt.Fprintf(writer, "\n# Package prefixes to try on short service names\n")
+		for _, pkg := range packages {
+			fmt.Fprintf(writer, "@%s\n", pkg)
+		}
+	}
+
 	// Write global methods
 	globalMethods := make([]string, 0)
 	for method := range e.operations {
@@ -284,6 +510,7 @@ func (e *WordlistExtractor) WriteEnhancedWordlist(writer *bufio.Writer, addPatte
 	fmt.Fprintf(writer, "\n# Summary:\n")
 	fmt.Fprintf(writer, "# - %d services with specific methods\n", len(servicesWithMethods))
 	fmt.Fprintf(writer, "# - %d services without methods\n", len(servicesWithoutMethods))
+	fmt.Fprintf(writer, "# - %d package prefixes\n", len(packages))
 	fmt.Fprintf(writer, "# - %d global methods\n", len(globalMethods))
 }
 
@@ -298,18 +525,53 @@ func stringInSlice(slice []string, item string) bool {
 
 // runWordlistCommand handles the wordlist subcommand
 func runWordlistCommand(args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "merge":
+			runWordlistMerge(args[1:])
+			return
+		case "learn":
+			runWordlistLearn(args[1:])
+			return
+		case "list":
+			runWordlistList()
+			return
+		}
+	}
+
 	if len(args) < 1 {
 		fmt.Println("Usage: grpc-scanner wordlist [options]")
+		fmt.Println("       grpc-scanner wordlist merge [-output=file] <wordlist>...")
+		fmt.Println("       grpc-scanner wordlist learn -from=<results/*.json> [-output=file]")
+		fmt.Println("       grpc-scanner wordlist list")
 		fmt.Println("\nGenerate wordlists from API documentation")
 		fmt.Println("\nOptions:")
-		fmt.Println("  -url string     URL of API documentation to extract from")
-		fmt.Println("  -input string   Local file to extract from")
+		fmt.Println("  -url string     URL of API documentation to crawl (same origin only)")
+		fmt.Println("  -depth int      Link depth to follow from -url (default: 2)")
+		fmt.Println("  -max-pages int  Maximum number of pages to fetch (default: 50)")
+		fmt.Println("  -timeout int    Timeout per request in seconds (default: 10)")
+		fmt.Println("  -header string  Extra request header as \"Name: value\" (repeatable)")
+		fmt.Println("  -cookie string  Cookie header to send with every request")
+		fmt.Println("  -ignore-robots  Crawl paths disallowed by robots.txt")
+		fmt.Println("  -input string   Local file or directory to extract from (OpenAPI .json/.yaml files are")
+		fmt.Println("                  imported by operation, .proto files and descriptor sets by service,")
+		fmt.Println("                  .js/.mjs bundles and source maps by generated client stub, .apk/.aab/.ipa")
+		fmt.Println("                  packages and Mach-O binaries by compiled stub, .har and .pcap/.pcapng")
+		fmt.Println("                  captures by observed request)")
 		fmt.Println("  -output string  Output wordlist file (default: api_wordlist.txt)")
+		fmt.Println("  -calls string   Where to save requests captured from .har/.pcap input for -call -replay")
+		fmt.Println("                  (default: captured_calls.json)")
 		fmt.Println("  -enhanced       Generate enhanced format with methods (default: true)")
 		fmt.Println("  -patterns       Add common gRPC patterns (default: true)")
+		fmt.Println("  -scores         Annotate each entry with its confidence score (# score=N)")
 		fmt.Println("  -v              Verbose output")
-		fmt.Println("\nExample:")
+		fmt.Println("\nExamples:")
 		fmt.Println("  grpc-scanner wordlist -url=https://api.example.com/docs -output=wordlist.txt")
+		fmt.Println("  grpc-scanner wordlist -url=https://app.example.com/ -depth=1 -cookie=\"session=abc\" -output=wordlist.txt")
+		fmt.Println("  grpc-scanner wordlist -input=openapi.yaml -output=wordlist.txt")
+		fmt.Println("  grpc-scanner wordlist -input=./protos -output=wordlist.txt")
+		fmt.Println("  grpc-scanner wordlist -input=app.apk -output=wordlist.txt")
+		fmt.Println("  grpc-scanner wordlist -input=session.har -output=wordlist.txt -calls=captured_calls.json")
 		return
 	}
 
@@ -317,9 +579,12 @@ func runWordlistCommand(args []string) {
 		url      string
 		input    string
 		output   = "api_wordlist.txt"
+		calls    = "captured_calls.json"
 		enhanced = true
 		patterns = true
+		scores   = false
 		verbose  = false
+		crawl    = DefaultCrawlOptions()
 	)
 
 	// Parse wordlist-specific flags
@@ -331,14 +596,36 @@ func runWordlistCommand(args []string) {
 			input = strings.TrimPrefix(arg, "-input=")
 		} else if strings.HasPrefix(arg, "-output=") {
 			output = strings.TrimPrefix(arg, "-output=")
+		} else if strings.HasPrefix(arg, "-calls=") {
+			calls = strings.TrimPrefix(arg, "-calls=")
 		} else if arg == "-enhanced=false" {
 			enhanced = false
 		} else if arg == "-patterns=false" {
 			patterns = false
 		} else if arg == "-v" {
 			verbose = true
+		} else if arg == "-scores" {
+			scores = true
+		} else if strings.HasPrefix(arg, "-depth=") {
+			crawl.MaxDepth = parseIntFlag("-depth", strings.TrimPrefix(arg, "-depth="), 0)
+		} else if strings.HasPrefix(arg, "-max-pages=") {
+			crawl.MaxPages = parseIntFlag("-max-pages", strings.TrimPrefix(arg, "-max-pages="), 1)
+		} else if strings.HasPrefix(arg, "-timeout=") {
+			seconds := parseIntFlag("-timeout", strings.TrimPrefix(arg, "-timeout="), 1)
+			crawl.Timeout = time.Duration(seconds) * time.Second
+		} else if strings.HasPrefix(arg, "-header=") {
+			name, value, ok := strings.Cut(strings.TrimPrefix(arg, "-header="), ":")
+			if !ok {
+				log.Fatalf("Invalid -header %q (use \"Name: value\")", strings.TrimPrefix(arg, "-header="))
+			}
+			crawl.Headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+		} else if strings.HasPrefix(arg, "-cookie=") {
+			crawl.Headers.Add("Cookie", strings.TrimPrefix(arg, "-cookie="))
+		} else if arg == "-ignore-robots" {
+			crawl.IgnoreRobots = true
 		}
 	}
+	crawl.Verbose = verbose
 
 	if url == "" && input == "" {
 		log.Fatal("Please provide either -url or -input")
@@ -351,9 +638,20 @@ func runWordlistCommand(args []string) {
 		if verbose {
 			log.Printf("Extracting from URL: %s", url)
 		}
-		if err := extractor.ExtractFromURL(url); err != nil {
+		pages, err := extractor.ExtractFromURL(url, crawl)
+		if err != nil {
 			log.Fatalf("Failed to extract from URL: %v", err)
 		}
+		if verbose {
+			log.Printf("Crawled %d pages", pages)
+		}
+	} else if info, err := os.Stat(input); err == nil && info.IsDir() {
+		if verbose {
+			log.Printf("Extracting from directory: %s", input)
+		}
+		if err := extractor.ExtractFromDir(input); err != nil {
+			log.Fatalf("Failed to extract from directory: %v", err)
+		}
 	} else {
 		if verbose {
 			log.Printf("Extracting from file: %s", input)
@@ -364,8 +662,8 @@ func runWordlistCommand(args []string) {
 	}
 
 	if verbose {
-		log.Printf("Found %d services, %d resources, %d operations", 
-			len(extractor.services), len(extractor.resources), len(extractor.operations))
+		log.Printf("Found %d services, %d resources, %d operations, %d packages",
+			len(extractor.services), len(extractor.resources), len(extractor.operations), len(extractor.packages))
 		
 		// Count methods
 		totalMethods := 0
@@ -386,7 +684,7 @@ func runWordlistCommand(args []string) {
 	
 	if enhanced {
 		// Write enhanced format
-		extractor.WriteEnhancedWordlist(writer, patterns)
+		extractor.WriteEnhancedWordlist(writer, patterns, scores)
 	} else {
 		// Write simple format (not implemented in this version, but could be added)
 		fmt.Fprintf(writer, "# Generated wordlist from API documentation\n")
@@ -394,7 +692,7 @@ func runWordlistCommand(args []string) {
 		for service := range extractor.services {
 			services = append(services, service)
 		}
-		sort.Strings(services)
+		extractor.rankServices(services)
 		for _, service := range services {
 			fmt.Fprintf(writer, "%s\n", service)
 		}
@@ -402,4 +700,25 @@ func runWordlistCommand(args []string) {
 
 	writer.Flush()
 	fmt.Printf("Wordlist saved to %s\n", output)
-}
\ No newline at end of file
+
+	if len(extractor.calls) > 0 {
+		if err := saveCapturedCalls(calls, extractor.calls); err != nil {
+			log.Fatalf("%v", err)
+		}
+		fmt.Printf("Saved %d captured requests to %s (replay with -call=Service/Method -replay=%s)\n",
+			len(extractor.calls), calls, calls)
+	}
+}
+
+// parseIntFlag parses the integer value of a subcommand flag, exiting on
+// anything that is not a whole number of at least min
+func parseIntFlag(name, value string, min int) int {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: not a number", name, value)
+	}
+	if n < min {
+		log.Fatalf("Invalid %s %d: must be at least %d", name, n, min)
+	}
+	return n
+}
@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Connect protocol constants
+const (
+	connectProtocolVersion = "1"
+	connectEndStreamFlag   = 0x02
+	maxConnectBody         = 4 << 20
+)
+
+// connectConn sends calls with the Connect protocol (connectrpc). Unary
+// calls are plain POSTs of an unframed application/proto or
+// application/json body; streams use enveloped application/connect+<codec>.
+type connectConn struct {
+	baseURL string
+	json    bool
+	client  *http.Client
+}
+
+func newConnectConn(target string, useJSON, useTLS bool) *connectConn {
+	return &connectConn{
+		baseURL: httpBaseURL(target, useTLS),
+		json:    useJSON,
+		client:  newHTTPClient(),
+	}
+}
+
+func (c *connectConn) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// connectJSONCodec encodes messages as protobuf JSON
+type connectJSONCodec struct{}
+
+func (connectJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+	return protojson.Marshal(msg)
+}
+
+func (connectJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+	return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, msg)
+}
+
+func (connectJSONCodec) Name() string { return "json" }
+
+// callOptions parses call options, defaulting to JSON on a connect-json
+// connection. A forced codec (e.g. the raw codec) always wins.
+func (c *connectConn) callOptions(opts []grpc.CallOption) webCallOptions {
+	o := parseWebCallOptions(opts)
+	if o.codec == nil && c.json {
+		o.codec = connectJSONCodec{}
+	}
+	return o
+}
+
+func connectCodecName(o webCallOptions) string {
+	if o.codec != nil {
+		return o.codec.Name()
+	}
+	return "proto"
+}
+
+// newRequest builds a Connect POST with timeout and outgoing metadata headers
+func (c *connectConn) newRequest(ctx context.Context, method, contentType string, body []byte) (*http.Request, error) {
+	if !strings.HasPrefix(method, "/") {
+		method = "/" + method
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%sinvalid request: %v", transportErrorPrefix, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Connect-Timeout-Ms", strconv.FormatInt(time.Until(deadline).Milliseconds(), 10))
+	}
+	setOutgoingHeaders(ctx, req.Header)
+	return req, nil
+}
+
+// Invoke performs a unary call
+func (c *connectConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	_, err := c.unary(ctx, method, args, reply, opts...)
+	return err
+}
+
+// unary performs a unary call. refused reports a 415 with Accept-Post, which
+// a Connect handler sends when the procedure is streaming and only takes
+// enveloped application/connect+<codec> requests.
+func (c *connectConn) unary(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) (refused bool, err error) {
+	o := c.callOptions(opts)
+	payload, err := o.marshal(args)
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "grpc: error while marshaling: %v", err)
+	}
+
+	contentType := "application/" + connectCodecName(o)
+	req, err := c.newRequest(ctx, method, contentType, payload)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Connect-Protocol-Version", connectProtocolVersion)
+
+	httpResp, err := c.client.Do(req)
+	if err != nil {
+		return false, transportError(ctx, err)
+	}
+	defer httpResp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, maxConnectBody))
+	header, trailer := connectUnaryMD(httpResp.Header)
+	o.capture(header, trailer)
+	if err != nil {
+		return false, transportError(ctx, err)
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		refused = httpResp.StatusCode == http.StatusUnsupportedMediaType && httpResp.Header.Get("Accept-Post") != ""
+		return refused, connectHTTPError(httpResp, body)
+	}
+	if !strings.HasPrefix(httpResp.Header.Get("Content-Type"), contentType) {
+		return false, status.Errorf(codes.Unknown, "transport: received unexpected content-type %q", httpResp.Header.Get("Content-Type"))
+	}
+	if err := o.unmarshal(body, reply); err != nil {
+		return false, status.Errorf(codes.Internal, "grpc: failed to unmarshal the received message: %v", err)
+	}
+	return false, nil
+}
+
+// NewStream opens a stream. Like gRPC-Web, messages are buffered and posted
+// as one enveloped request, which Connect serves over HTTP/1.1 as well.
+func (c *connectConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return &httpStream{ctx: ctx, conn: c, method: method, opts: c.callOptions(opts)}, nil
+}
+
+// send posts enveloped messages as a Connect streaming request
+func (c *connectConn) send(ctx context.Context, method string, payloads [][]byte, o webCallOptions) (*webResponse, error) {
+	var body bytes.Buffer
+	for _, payload := range payloads {
+		body.Write(webFrame(webDataFrame, payload))
+	}
+
+	contentType := "application/connect+" + connectCodecName(o)
+	req, err := c.newRequest(ctx, method, contentType, body.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := c.client.Do(req)
+	if err != nil {
+		return nil, transportError(ctx, err)
+	}
+
+	header := httpHeaderMD(httpResp.Header)
+	if httpResp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(httpResp.Body, maxConnectBody))
+		httpResp.Body.Close()
+		o.capture(header, nil)
+		return nil, connectHTTPError(httpResp, data)
+	}
+	if !strings.HasPrefix(httpResp.Header.Get("Content-Type"), "application/connect+") {
+		httpResp.Body.Close()
+		o.capture(header, nil)
+		return nil, status.Errorf(codes.Unknown, "transport: received unexpected content-type %q", httpResp.Header.Get("Content-Type"))
+	}
+
+	return &webResponse{
+		ctx:      ctx,
+		body:     httpResp.Body,
+		reader:   bufio.NewReader(httpResp.Body),
+		httpRsp:  httpResp,
+		header:   header,
+		trailer:  metadata.MD{},
+		endFlag:  connectEndStreamFlag,
+		parseEnd: connectEndStreamMD,
+	}, nil
+}
+
+// connectUnaryMD splits unary response headers into headers and the
+// "Trailer-" prefixed trailers
+func connectUnaryMD(h http.Header) (metadata.MD, metadata.MD) {
+	header, trailer := metadata.MD{}, metadata.MD{}
+	for key, values := range h {
+		key = strings.ToLower(key)
+		if name := strings.TrimPrefix(key, "trailer-"); name != key {
+			trailer.Append(name, values...)
+			continue
+		}
+		header.Append(key, values...)
+	}
+	return header, trailer
+}
+
+// connectWireError is the JSON error body of the Connect protocol
+type connectWireError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"details"`
+}
+
+// Connect error code names
+var connectCodes = map[string]codes.Code{
+	"canceled":            codes.Canceled,
+	"unknown":             codes.Unknown,
+	"invalid_argument":    codes.InvalidArgument,
+	"deadline_exceeded":   codes.DeadlineExceeded,
+	"not_found":           codes.NotFound,
+	"already_exists":      codes.AlreadyExists,
+	"permission_denied":   codes.PermissionDenied,
+	"resource_exhausted":  codes.ResourceExhausted,
+	"failed_precondition": codes.FailedPrecondition,
+	"aborted":             codes.Aborted,
+	"out_of_range":        codes.OutOfRange,
+	"unimplemented":       codes.Unimplemented,
+	"internal":            codes.Internal,
+	"unavailable":         codes.Unavailable,
+	"data_loss":           codes.DataLoss,
+	"unauthenticated":     codes.Unauthenticated,
+}
+
+// status converts the wire error, including its details, to a gRPC status
+func (e *connectWireError) status() *status.Status {
+	code, ok := connectCodes[e.Code]
+	if !ok {
+		code = codes.Unknown
+	}
+	st := &spb.Status{Code: int32(code), Message: e.Message}
+	for _, detail := range e.Details {
+		value, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(detail.Value, "="))
+		if err != nil {
+			continue
+		}
+		st.Details = append(st.Details, &anypb.Any{TypeUrl: "type.googleapis.com/" + detail.Type, Value: value})
+	}
+	return status.FromProto(st)
+}
+
+// connectHTTPError converts a non-200 response to a status. Connect servers
+// send a JSON error body; anything else is a plain HTTP response. A 415 with
+// Accept-Post is a Connect handler refusing the content type, which happens
+// when a streaming procedure is called unary, so the procedure exists.
+func connectHTTPError(httpResp *http.Response, body []byte) error {
+	if strings.HasPrefix(httpResp.Header.Get("Content-Type"), "application/json") {
+		var wire connectWireError
+		if json.Unmarshal(body, &wire) == nil && wire.Code != "" {
+			return wire.status().Err()
+		}
+	}
+	if accepts := httpResp.Header.Get("Accept-Post"); httpResp.StatusCode == http.StatusUnsupportedMediaType && accepts != "" {
+		return status.Errorf(codes.Internal, "procedure does not accept this content type (accepts %s)", accepts)
+	}
+	return httpStatusError(httpResp)
+}
+
+// connectEndStreamMD converts the end-of-stream message into trailers with
+// grpc-status fields, so streams finish the same way as gRPC-Web
+func connectEndStreamMD(payload []byte) metadata.MD {
+	var end struct {
+		Error    *connectWireError   `json:"error"`
+		Metadata map[string][]string `json:"metadata"`
+	}
+	md := metadata.MD{}
+	if err := json.Unmarshal(payload, &end); err != nil {
+		md.Set("grpc-status", strconv.Itoa(int(codes.Internal)))
+		md.Set("grpc-message", url.PathEscape("invalid end of stream message: "+err.Error()))
+		return md
+	}
+
+	for key, values := range end.Metadata {
+		md.Append(strings.ToLower(key), values...)
+	}
+	if end.Error == nil {
+		md.Set("grpc-status", strconv.Itoa(int(codes.OK)))
+		return md
+	}
+
+	st := end.Error.status()
+	md.Set("grpc-status", strconv.Itoa(int(st.Code())))
+	md.Set("grpc-message", url.PathEscape(st.Message()))
+	if len(st.Proto().GetDetails()) > 0 {
+		if raw, err := proto.Marshal(st.Proto()); err == nil {
+			md.Set("grpc-status-details-bin", base64.StdEncoding.EncodeToString(raw))
+		}
+	}
+	return md
+}
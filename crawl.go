@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxCrawlBody caps how much of one crawled response is read
+const maxCrawlBody = 10 << 20
+
+// CrawlOptions bounds the documentation crawler
+type CrawlOptions struct {
+	MaxDepth     int
+	MaxPages     int
+	Timeout      time.Duration
+	Headers      http.Header
+	IgnoreRobots bool
+	Verbose      bool
+	// Client is the base HTTP client, for example to crawl an httptest
+	// server. The crawl uses a copy with its own redirect policy and Timeout.
+	Client *http.Client
+}
+
+// DefaultCrawlOptions follows links two levels deep and fetches at most 50 pages
+func DefaultCrawlOptions() CrawlOptions {
+	return CrawlOptions{MaxDepth: 2, MaxPages: 50, Timeout: 10 * time.Second, Headers: http.Header{}}
+}
+
+// sourceMapPattern finds the source map comment at the end of a bundle
+var sourceMapPattern = regexp.MustCompile(`//[#@]\s*sourceMappingURL=(\S+)`)
+
+// ExtractFromURL crawls startURL and the same-origin pages, scripts and
+// documents it links to, breadth first, routing each response to the
+// matching extractor by content type. It returns the number of pages fetched.
+func (e *WordlistExtractor) ExtractFromURL(startURL string, opts CrawlOptions) (int, error) {
+	start, err := url.Parse(startURL)
+	if err != nil || start.Host == "" {
+		return 0, fmt.Errorf("invalid URL %q", startURL)
+	}
+	start.Fragment = ""
+
+	origin := start.Scheme + "://" + start.Host
+	// Work on a copy of an injected client so the same-origin redirect policy
+	// and the timeout apply to it too, without changing the caller's client
+	client := &http.Client{}
+	if opts.Client != nil {
+		*client = *opts.Client
+	}
+	if opts.Timeout > 0 {
+		client.Timeout = opts.Timeout
+	}
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme+"://"+req.URL.Host != origin {
+			return fmt.Errorf("redirect to another origin: %s", req.URL)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		return nil
+	}
+	c := &crawler{extractor: e, opts: opts, client: client, origin: origin}
+	if !opts.IgnoreRobots {
+		c.robots = c.fetchRobots()
+	}
+
+	type queued struct {
+		url   string
+		depth int
+	}
+	queue := []queued{{start.String(), 0}}
+	seen := map[string]bool{start.String(): true}
+	fetched := 0
+
+	for len(queue) > 0 && fetched < opts.MaxPages {
+		item := queue[0]
+		queue = queue[1:]
+		if !c.allowed(item.url) {
+			if opts.Verbose {
+				log.Printf("Skipping %s (disallowed by robots.txt)", item.url)
+			}
+			continue
+		}
+
+		links, err := c.fetch(item.url)
+		if err != nil {
+			// Only a failure on the start page is fatal
+			if fetched == 0 && item.url == start.String() {
+				return 0, err
+			}
+			if opts.Verbose {
+				log.Printf("Failed to fetch %s: %v", item.url, err)
+			}
+			continue
+		}
+		fetched++
+
+		if item.depth >= opts.MaxDepth {
+			continue
+		}
+		for _, link := range links {
+			if !seen[link] {
+				seen[link] = true
+				queue = append(queue, queued{link, item.depth + 1})
+			}
+		}
+	}
+	return fetched, nil
+}
+
+// crawler holds the state of one ExtractFromURL crawl
+type crawler struct {
+	extractor *WordlistExtractor
+	opts      CrawlOptions
+	client    *http.Client
+	origin    string
+	robots    []robotsRule
+}
+
+// robotsRule is one Allow or Disallow line that applies to us
+type robotsRule struct {
+	prefix string
+	allow  bool
+}
+
+// fetchRobots reads the Allow/Disallow rules of the "*" and "grpc-scan"
+// groups of robots.txt
+func (c *crawler) fetchRobots() []robotsRule {
+	resp, err := c.get(c.origin + "/robots.txt")
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	var rules []robotsRule
+	applies, inAgents := false, false
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxCrawlBody))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if pos := strings.IndexByte(line, '#'); pos >= 0 {
+			line = line[:pos]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive User-agent lines share one group
+			if !inAgents {
+				applies = false
+			}
+			inAgents = true
+			if agent := strings.ToLower(value); agent == "*" || strings.Contains(agent, "grpc-scan") {
+				applies = true
+			}
+		case "allow", "disallow":
+			inAgents = false
+			if applies && value != "" {
+				rules = append(rules, robotsRule{prefix: value, allow: key == "allow"})
+			}
+		default:
+			inAgents = false
+		}
+	}
+	return rules
+}
+
+// allowed applies the longest matching robots.txt rule
+func (c *crawler) allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	target := u.EscapedPath()
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+	}
+
+	allow, longest := true, -1
+	for _, rule := range c.robots {
+		prefix := strings.TrimSuffix(rule.prefix, "*")
+		if strings.HasPrefix(target, prefix) && len(prefix) > longest {
+			allow, longest = rule.allow, len(prefix)
+		}
+	}
+	return allow
+}
+
+func (c *crawler) get(rawURL string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.opts.Headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", "grpc-scan")
+	}
+	return c.client.Do(req)
+}
+
+// fetch downloads one URL, hands it to the extractor and returns the
+// same-origin links found in it
+func (c *crawler) fetch(rawURL string) ([]string, error) {
+	resp, err := c.get(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL: HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCrawlBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	ext := strings.ToLower(path.Ext(resp.Request.URL.Path))
+	if c.opts.Verbose {
+		log.Printf("Fetched %s (%s, %d bytes)", rawURL, contentType, len(body))
+	}
+
+	e := c.extractor
+	switch {
+	case strings.Contains(contentType, "html"):
+		doc, err := html.Parse(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %v", err)
+		}
+		e.extractFromNode(doc)
+		return c.links(resp.Request.URL, htmlLinks(doc)), nil
+	case strings.Contains(contentType, "javascript") || jsExts[ext]:
+		e.ExtractFromJS(string(body))
+		if match := sourceMapPattern.FindSubmatch(body); match != nil {
+			return c.links(resp.Request.URL, []string{string(match[1])}), nil
+		}
+	case ext == ".map":
+		e.ExtractFromSourceMap(body)
+	case ext == ".proto":
+		e.ExtractFromProto(string(body))
+	case strings.Contains(contentType, "json") || strings.Contains(contentType, "yaml") || ext == ".json" || ext == ".yaml" || ext == ".yml":
+		if e.ExtractFromOpenAPI(body) != nil {
+			e.extractFromText(string(body))
+		}
+	default:
+		for _, line := range strings.Split(string(body), "\n") {
+			e.extractFromText(line)
+		}
+	}
+	return nil, nil
+}
+
+// links resolves references against base and keeps the same-origin ones
+func (c *crawler) links(base *url.URL, refs []string) []string {
+	var out []string
+	for _, ref := range refs {
+		u, err := base.Parse(strings.TrimSpace(ref))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		u.Fragment = ""
+		if u.Scheme+"://"+u.Host == c.origin {
+			out = append(out, u.String())
+		}
+	}
+	return out
+}
+
+// htmlLinks collects the anchors, stylesheet-style links and scripts of a page
+func htmlLinks(n *html.Node) []string {
+	var refs []string
+	if n.Type == html.ElementNode {
+		attr := ""
+		switch n.Data {
+		case "a", "link":
+			attr = "href"
+		case "script":
+			attr = "src"
+		}
+		for _, a := range n.Attr {
+			if attr != "" && a.Key == attr && a.Val != "" {
+				refs = append(refs, a.Val)
+			}
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		refs = append(refs, htmlLinks(child)...)
+	}
+	return refs
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// docSite serves a small documentation site and records the paths fetched.
+// A page body of "redirect:URL" answers with a redirect to URL.
+type docSite struct {
+	*httptest.Server
+	mu      sync.Mutex
+	fetched map[string]int
+}
+
+func newDocSite(t *testing.T, pages map[string]string, contentTypes map[string]string) *docSite {
+	t.Helper()
+	site := &docSite{fetched: make(map[string]int)}
+	site.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		site.fetched[r.URL.Path]++
+		site.mu.Unlock()
+
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if location, ok := strings.CutPrefix(body, "redirect:"); ok {
+			http.Redirect(w, r, location, http.StatusFound)
+			return
+		}
+		contentType := contentTypes[r.URL.Path]
+		if contentType == "" {
+			contentType = "text/html; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+func (s *docSite) wasFetched(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetched[path] > 0
+}
+
+func crawlOptions(client *http.Client) CrawlOptions {
+	opts := DefaultCrawlOptions()
+	opts.Client = client
+	return opts
+}
+
+func TestCrawlDepthLimit(t *testing.T) {
+	site := newDocSite(t, map[string]string{
+		"/":       `<a href="/level1">next</a>`,
+		"/level1": `<a href="/level2">next</a>`,
+		"/level2": `<a href="/level3">next</a>`,
+		"/level3": `<p>too deep</p>`,
+	}, nil)
+
+	opts := crawlOptions(site.Client())
+	opts.MaxDepth = 2
+	pages, err := NewWordlistExtractor().ExtractFromURL(site.URL+"/", opts)
+	if err != nil {
+		t.Fatalf("ExtractFromURL: %v", err)
+	}
+	if pages != 3 {
+		t.Errorf("fetched %d pages, want 3", pages)
+	}
+	if !site.wasFetched("/level2") {
+		t.Error("/level2 at depth 2 was not fetched")
+	}
+	if site.wasFetched("/level3") {
+		t.Error("/level3 beyond -depth was fetched")
+	}
+}
+
+func TestCrawlPageLimit(t *testing.T) {
+	site := newDocSite(t, map[string]string{
+		"/":  `<a href="/a">a</a><a href="/b">b</a><a href="/c">c</a>`,
+		"/a": `<p>a</p>`,
+		"/b": `<p>b</p>`,
+		"/c": `<p>c</p>`,
+	}, nil)
+
+	opts := crawlOptions(site.Client())
+	opts.MaxPages = 2
+	pages, err := NewWordlistExtractor().ExtractFromURL(site.URL+"/", opts)
+	if err != nil {
+		t.Fatalf("ExtractFromURL: %v", err)
+	}
+	if pages != 2 {
+		t.Errorf("fetched %d pages, want 2", pages)
+	}
+	if site.wasFetched("/b") || site.wasFetched("/c") {
+		t.Error("pages beyond -max-pages were fetched")
+	}
+}
+
+func TestCrawlRobots(t *testing.T) {
+	site := newDocSite(t, map[string]string{
+		"/robots.txt": "User-agent: other\nDisallow: /\n\nUser-agent: *\nDisallow: /private\nAllow: /private/open\n",
+		"/": `<a href="/private/secret">secret</a>
+			<a href="/private/open/page">open</a>
+			<a href="/public">public</a>`,
+		"/private/secret":    `<p>secret</p>`,
+		"/private/open/page": `<p>open</p>`,
+		"/public":            `<p>public</p>`,
+	}, map[string]string{"/robots.txt": "text/plain"})
+
+	opts := crawlOptions(site.Client())
+	if _, err := NewWordlistExtractor().ExtractFromURL(site.URL+"/", opts); err != nil {
+		t.Fatalf("ExtractFromURL: %v", err)
+	}
+	if site.wasFetched("/private/secret") {
+		t.Error("Disallow: /private was not respected")
+	}
+	if !site.wasFetched("/private/open/page") {
+		t.Error("the longer Allow: /private/open did not take precedence")
+	}
+	if !site.wasFetched("/public") {
+		t.Error("/public was not fetched")
+	}
+
+	opts.IgnoreRobots = true
+	if _, err := NewWordlistExtractor().ExtractFromURL(site.URL+"/", opts); err != nil {
+		t.Fatalf("ExtractFromURL: %v", err)
+	}
+	if !site.wasFetched("/private/secret") {
+		t.Error("-ignore-robots still skipped a disallowed page")
+	}
+}
+
+func TestCrawlContentTypeRouting(t *testing.T) {
+	site := newDocSite(t, map[string]string{
+		"/": `<html><body>
+			<pre>class InventoryService {
+  listItems() {
+}</pre>
+			<a href="/openapi.json">API</a>
+			<script src="/static/app.js"></script>
+			</body></html>`,
+		"/openapi.json":          `{"swagger": "2.0", "paths": {"/v1/orders": {"get": {"operationId": "OrderService_ListOrders"}}}}`,
+		"/static/app.js":         "const path = '/acme.js.v1.BundleService/GetBundle';\n//# sourceMappingURL=app.js.map\n",
+		"/static/app.js.map":     `{"version": 3, "sources": ["client.ts"], "sourcesContent": ["const p = '/acme.map.v1.MapService/GetMap';"]}`,
+		"/static/unlinked.proto": `service Unlinked {}`,
+	}, map[string]string{
+		"/openapi.json":      "application/json",
+		"/static/app.js":     "application/javascript",
+		"/static/app.js.map": "application/json",
+	})
+
+	e := NewWordlistExtractor()
+	pages, err := e.ExtractFromURL(site.URL+"/", crawlOptions(site.Client()))
+	if err != nil {
+		t.Fatalf("ExtractFromURL: %v", err)
+	}
+	if pages != 4 {
+		t.Errorf("fetched %d pages, want 4", pages)
+	}
+
+	for service, method := range map[string]string{
+		"InventoryService":         "ListItems",  // HTML code block
+		"OrderService":             "ListOrders", // OpenAPI JSON
+		"acme.js.v1.BundleService": "GetBundle",  // JavaScript stub
+		"acme.map.v1.MapService":   "GetMap",     // source map found through sourceMappingURL
+	} {
+		if !e.services[service] {
+			t.Errorf("service %s was not extracted", service)
+		} else if !e.serviceMethods[service][method] {
+			t.Errorf("method %s/%s was not extracted", service, method)
+		}
+	}
+	if site.wasFetched("/static/unlinked.proto") {
+		t.Error("an unlinked page was fetched")
+	}
+}
+
+func TestCrawlStaysOnOrigin(t *testing.T) {
+	other := newDocSite(t, map[string]string{"/": `<p>other</p>`, "/landing": `<p>other</p>`}, nil)
+	site := newDocSite(t, map[string]string{
+		"/":         fmt.Sprintf(`<a href="%s/">other</a><a href="/redirect">redirect</a>`, other.URL),
+		"/redirect": "redirect:" + other.URL + "/landing",
+	}, nil)
+
+	// An injected client without a redirect policy must still stay on origin
+	client := &http.Client{}
+	if _, err := NewWordlistExtractor().ExtractFromURL(site.URL+"/", crawlOptions(client)); err != nil {
+		t.Fatalf("ExtractFromURL: %v", err)
+	}
+	if other.wasFetched("/") {
+		t.Error("a link to another origin was followed")
+	}
+	if other.wasFetched("/landing") {
+		t.Error("a redirect to another origin was followed")
+	}
+	if client.CheckRedirect != nil || client.Timeout != 0 {
+		t.Error("the injected client was modified")
+	}
+}
+
+func TestCrawlTimeoutAppliesToInjectedClient(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	opts := crawlOptions(&http.Client{})
+	opts.Timeout = 100 * time.Millisecond
+	opts.IgnoreRobots = true
+	done := make(chan error, 1)
+	go func() {
+		_, err := NewWordlistExtractor().ExtractFromURL(slow.URL+"/", opts)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("ExtractFromURL succeeded against a server that never answers")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the crawl timeout was not applied to the injected client")
+	}
+}
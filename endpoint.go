@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// endpointProbeTimeout bounds each pre-flight probe, so a server that never
+// answers does not eat the whole scan timeout
+const endpointProbeTimeout = 3 * time.Second
+
+// Endpoint classes reported by the pre-flight
+const (
+	endpointGRPC        = "grpc"
+	endpointGRPCWeb     = "grpc-web"
+	endpointConnect     = "connect"
+	endpointHTTP1       = "http1"
+	endpointHTTP2       = "http2"
+	endpointTLSOnly     = "tls-only"
+	endpointNonHTTP     = "non-http"
+	endpointUnreachable = "unreachable"
+)
+
+var endpointDescriptions = map[string]string{
+	endpointGRPC:        "native gRPC service",
+	endpointGRPCWeb:     "gRPC-Web service",
+	endpointConnect:     "Connect service",
+	endpointHTTP1:       "REST/HTTP1 service",
+	endpointHTTP2:       "HTTP/2 service (not gRPC)",
+	endpointTLSOnly:     "TLS-only service (retry with -tls)",
+	endpointNonHTTP:     "non-HTTP service",
+	endpointUnreachable: "no service listening",
+}
+
+// EndpointInfo is the pre-flight classification of a target
+type EndpointInfo struct {
+	Class      string `json:"class"`
+	Transport  string `json:"transport,omitempty"`
+	TLS        bool   `json:"tls"`
+	ALPN       string `json:"alpn,omitempty"`
+	HTTP1      bool   `json:"http1"`
+	HTTP2      bool   `json:"http2"`
+	Server     string `json:"server,omitempty"`
+	StatusLine string `json:"status_line,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// IsGRPC reports whether any gRPC flavor answered
+func (e *EndpointInfo) IsGRPC() bool {
+	return e.Class == endpointGRPC || e.Class == endpointGRPCWeb || e.Class == endpointConnect
+}
+
+// Describe returns a one-line summary with the server header and status line
+func (e *EndpointInfo) Describe() string {
+	desc := endpointDescriptions[e.Class]
+	if e.Transport != "" && e.Transport != transportNative && e.Transport != e.Class {
+		desc += " via " + e.Transport
+	}
+	var extra []string
+	if e.Server != "" {
+		extra = append(extra, "Server: "+e.Server)
+	}
+	if e.StatusLine != "" {
+		extra = append(extra, e.StatusLine)
+	}
+	if len(extra) > 0 {
+		desc += " (" + strings.Join(extra, ", ") + ")"
+	}
+	return desc
+}
+
+// classifyEndpoint performs an HTTP/1.1 request and an HTTP/2 request (h2c
+// prior knowledge, or ALPN h2 with TLS), then tries the gRPC flavors the
+// transport allows: native needs HTTP/2, gRPC-Web and Connect work on either.
+func classifyEndpoint(ctx context.Context, target, transport string, useTLS bool) *EndpointInfo {
+	info := &EndpointInfo{TLS: useTLS}
+
+	dialCtx, cancel := context.WithTimeout(ctx, endpointProbeTimeout)
+	conn, err := (&net.Dialer{}).DialContext(dialCtx, "tcp", target)
+	cancel()
+	if err != nil {
+		info.Class = endpointUnreachable
+		info.Error = err.Error()
+		return info
+	}
+	conn.Close()
+
+	h1, h1Err := probeHTTP1(ctx, target, useTLS)
+	if h1 != nil {
+		info.HTTP1 = true
+		info.recordResponse(h1)
+	}
+	h2, _ := probeHTTP2(ctx, target, useTLS)
+	if h2 != nil {
+		info.HTTP2 = true
+		info.recordResponse(h2)
+	}
+
+	if !info.HTTP1 && !info.HTTP2 {
+		info.Error = h1Err.Error()
+		info.Class = endpointNonHTTP
+		if !useTLS {
+			if alpn, ok := tlsHandshake(ctx, target); ok {
+				info.Class = endpointTLSOnly
+				info.ALPN = alpn
+			}
+		}
+		return info
+	}
+
+	if info.Transport = probeGRPCFlavors(ctx, target, transport, useTLS, info.HTTP2); info.Transport != "" {
+		switch info.Transport {
+		case transportNative:
+			info.Class = endpointGRPC
+		case transportConnect, transportConnectJSON:
+			info.Class = endpointConnect
+		default:
+			info.Class = endpointGRPCWeb
+		}
+		return info
+	}
+
+	info.Class = endpointHTTP1
+	if info.HTTP2 {
+		info.Class = endpointHTTP2
+	}
+	return info
+}
+
+// recordResponse keeps the first status line and Server header seen
+func (e *EndpointInfo) recordResponse(resp *http.Response) {
+	if e.StatusLine == "" {
+		e.StatusLine = resp.Proto + " " + resp.Status
+	}
+	if e.Server == "" {
+		e.Server = resp.Header.Get("Server")
+	}
+	if e.ALPN == "" && resp.TLS != nil {
+		e.ALPN = resp.TLS.NegotiatedProtocol
+	}
+}
+
+// probeGRPCFlavors returns the first transport that gets a gRPC status back
+func probeGRPCFlavors(ctx context.Context, target, transport string, useTLS, hasHTTP2 bool) string {
+	candidates := httpFallbacks
+	switch transport {
+	case transportAuto:
+	case transportNative:
+		candidates = nil
+	default:
+		candidates = []string{transport}
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, endpointProbeTimeout)
+	defer cancel()
+
+	if hasHTTP2 && (transport == transportAuto || transport == transportNative) {
+		if native, err := dialNative(probeCtx, target, useTLS); err == nil {
+			err := probeTransport(probeCtx, native)
+			native.Close()
+			if err == nil || isGRPCStatus(err) {
+				return transportNative
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		conn := newHTTPConn(target, candidate, useTLS)
+		err := probeTransport(probeCtx, conn)
+		conn.Close()
+		if err == nil || isGRPCStatus(err) {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// probeHTTP1 sends GET / over HTTP/1.1 only
+func probeHTTP1(ctx context.Context, target string, useTLS bool) (*http.Response, error) {
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"http/1.1"}},
+		// A non-nil empty map disables the automatic HTTP/2 upgrade
+		TLSNextProto: map[string]func(string, *tls.Conn) http.RoundTripper{},
+	}
+	defer transport.CloseIdleConnections()
+	return probeGet(ctx, transport, httpBaseURL(target, useTLS))
+}
+
+// probeHTTP2 sends GET / over HTTP/2, starting with the client preface on a
+// plaintext connection or negotiating h2 through ALPN over TLS
+func probeHTTP2(ctx context.Context, target string, useTLS bool) (*http.Response, error) {
+	transport := &http2.Transport{
+		AllowHTTP:       true,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+			if !useTLS {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			}
+			conn, err := (&tls.Dialer{Config: cfg}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			if proto := conn.(*tls.Conn).ConnectionState().NegotiatedProtocol; proto != http2.NextProtoTLS {
+				conn.Close()
+				return nil, fmt.Errorf("server negotiated %q instead of h2", proto)
+			}
+			return conn, nil
+		},
+	}
+	defer transport.CloseIdleConnections()
+	return probeGet(ctx, transport, httpBaseURL(target, useTLS))
+}
+
+func probeGet(ctx context.Context, transport http.RoundTripper, baseURL string) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, endpointProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "grpc-scan")
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// tlsHandshake reports whether the target completes a TLS handshake
+func tlsHandshake(ctx context.Context, target string) (string, bool) {
+	ctx, cancel := context.WithTimeout(ctx, endpointProbeTimeout)
+	defer cancel()
+
+	dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true, NextProtos: []string{http2.NextProtoTLS, "http/1.1"}}}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return "", false
+	}
+	defer conn.Close()
+	return conn.(*tls.Conn).ConnectionState().NegotiatedProtocol, true
+}
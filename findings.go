@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Finding is a typed security observation derived from a scan
+type Finding struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Severity    string `json:"severity"`
+	Method      string `json:"method,omitempty"`
+	Evidence    string `json:"evidence"`
+	Remediation string `json:"remediation"`
+}
+
+// ProbeStatus records the status a confirmed method returned to an empty request
+type ProbeStatus struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message,omitempty"`
+	Details []StatusDetail `json:"details,omitempty"`
+}
+
+// Severity levels, ordered from least to most severe
+var severityLevels = []string{"info", "low", "medium", "high", "critical"}
+
+var (
+	// Method names that are public by design, such as login or liveness checks
+	publicMethodPattern = regexp.MustCompile(`(?i)^(login|logout|register|signup|signin|authenticate|createtoken|validate|verify|refresh|ping|health|check|echo|sayhello)`)
+	// Method names that usually guard sensitive data or actions
+	sensitiveMethodPattern = regexp.MustCompile(`(?i)(admin|secret|password|credential|token|key|delete|remove|export|internal|debug|config|profile|payment|user|account)`)
+)
+
+// severityRank returns the position of a severity in severityLevels, or -1
+func severityRank(severity string) int {
+	for i, level := range severityLevels {
+		if level == strings.ToLower(severity) {
+			return i
+		}
+	}
+	return -1
+}
+
+// recordMethodStatus stores the status a confirmed method returned
+func (s *Scanner) recordMethodStatus(service, method string, err error) {
+	st := status.Convert(err)
+
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	s.result.MethodStatus[service+"/"+method] = ProbeStatus{
+		Code:    st.Code().String(),
+		Message: st.Message(),
+		Details: decodeStatusDetails(st),
+	}
+}
+
+// addFinding appends a finding to the scan result
+func (s *Scanner) addFinding(finding Finding) {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	s.result.Findings = append(s.result.Findings, finding)
+}
+
+// analyzeFindings derives findings from the scan result, drops anything below
+// the configured minimum severity and sorts the rest by severity
+func (s *Scanner) analyzeFindings() {
+	if s.result.ReflectionEnabled {
+		s.addFinding(Finding{
+			ID:          "reflection-exposed",
+			Title:       "Server reflection is enabled",
+			Severity:    "medium",
+			Evidence:    fmt.Sprintf("ServerReflectionInfo listed %d services", len(s.result.AvailableServices)),
+			Remediation: "Disable the reflection service in production or restrict it to trusted callers.",
+		})
+	}
+
+	for _, admin := range s.result.AdminServices {
+		evidence := admin.Description
+		if len(admin.Methods) > 0 {
+			evidence += fmt.Sprintf(" (callable: %s)", strings.Join(admin.Methods, ", "))
+		}
+		s.addFinding(Finding{
+			ID:          "admin-service-exposed",
+			Title:       fmt.Sprintf("Admin/debug service %s is exposed", admin.Service),
+			Severity:    admin.Severity,
+			Method:      admin.Service,
+			Evidence:    evidence,
+			Remediation: "Serve admin and test services on a separate, internal-only listener.",
+		})
+	}
+
+	if cz := s.result.Channelz; cz != nil && len(cz.Sockets) > 0 {
+		peers := []string{}
+		for _, socket := range cz.Sockets {
+			peer := socket.Remote
+			if peer == "" {
+				peer = socket.Local
+			}
+			if socket.TLS != "" {
+				peer += " [" + socket.TLS + "]"
+			}
+			peers = append(peers, peer)
+		}
+		s.addFinding(Finding{
+			ID:          "channelz-internals-leaked",
+			Title:       "channelz reveals internal peer addresses",
+			Severity:    "high",
+			Method:      "grpc.channelz.v1.Channelz",
+			Evidence:    strings.Join(peers, ", "),
+			Remediation: "Do not register channelz on externally reachable servers.",
+		})
+	}
+
+	healthNames := []string{}
+	for service, state := range s.result.HealthStatus {
+		if service != "" && state != healthNotFound {
+			healthNames = append(healthNames, service)
+		}
+	}
+	if len(healthNames) > 0 {
+		sort.Strings(healthNames)
+		s.addFinding(Finding{
+			ID:          "health-service-enumeration",
+			Title:       "Health service discloses registered service names",
+			Severity:    "info",
+			Method:      "grpc.health.v1.Health/Check",
+			Evidence:    strings.Join(healthNames, ", "),
+			Remediation: "Only register externally relevant service names with the health server.",
+		})
+	}
+
+	methods := make([]string, 0, len(s.result.MethodStatus))
+	for fullMethod := range s.result.MethodStatus {
+		methods = append(methods, fullMethod)
+	}
+	sort.Strings(methods)
+
+	adminServices := make(map[string]bool)
+	for _, admin := range s.result.AdminServices {
+		adminServices[admin.Service] = true
+	}
+
+	for _, fullMethod := range methods {
+		probe := s.result.MethodStatus[fullMethod]
+		service, method := splitFullMethod(fullMethod)
+		if isStandardService(fullMethod) || adminServices[service] {
+			continue
+		}
+
+		// Any handler-level answer means the request got past authentication,
+		// not only OK: InvalidArgument or NotFound come from the handler too
+		if code, ok := parseCode(probe.Code); ok && reachedHandler(code) {
+			severity := "low"
+			if publicMethodPattern.MatchString(method) {
+				severity = "info"
+			} else if sensitiveMethodPattern.MatchString(fullMethod) {
+				severity = "high"
+			}
+			s.addFinding(Finding{
+				ID:          "method-callable-without-auth",
+				Title:       "Method is callable without authentication",
+				Severity:    severity,
+				Method:      fullMethod,
+				Evidence:    fmt.Sprintf("an empty request without credentials reached the handler and returned %s", code),
+				Remediation: "Require authentication for this method unless it is intentionally public.",
+			})
+		}
+	}
+
+	s.analyzeErrorLeaks()
+	s.analyzeResponseMetadata()
+	s.filterFindings()
+}
+
+// parseCode converts a recorded status code name back to its code
+func parseCode(name string) (codes.Code, bool) {
+	for code := codes.OK; code <= codes.Unauthenticated; code++ {
+		if code.String() == name {
+			return code, true
+		}
+	}
+	return codes.Unknown, false
+}
+
+// filterFindings sorts the findings by severity and drops those below the
+// minimum severity from the report. The complete list is kept for -fail-on.
+func (s *Scanner) filterFindings() {
+	s.allFindings = append([]Finding(nil), s.result.Findings...)
+	sort.SliceStable(s.allFindings, func(i, j int) bool {
+		return severityRank(s.allFindings[i].Severity) > severityRank(s.allFindings[j].Severity)
+	})
+
+	minRank := severityRank(s.minSeverity)
+	filtered := []Finding{}
+	for _, finding := range s.allFindings {
+		if severityRank(finding.Severity) >= minRank {
+			filtered = append(filtered, finding)
+		}
+	}
+	s.result.Findings = filtered
+}
+
+// hasFindingAtLeast reports whether any finding meets the given severity,
+// including findings hidden from the report by -min-severity
+func (s *Scanner) hasFindingAtLeast(severity string) bool {
+	rank := severityRank(severity)
+	for _, finding := range s.allFindings {
+		if severityRank(finding.Severity) >= rank {
+			return true
+		}
+	}
+	return false
+}
+
+// printFindings writes the findings section of the human output
+func (s *Scanner) printFindings() {
+	if len(s.result.Findings) == 0 {
+		return
+	}
+
+	fmt.Printf("\nFindings (%d):\n", len(s.result.Findings))
+	for _, finding := range s.result.Findings {
+		fmt.Printf("\n   [%s] %s\n", strings.ToUpper(finding.Severity), finding.Title)
+		fmt.Printf("      ID:          %s\n", finding.ID)
+		if finding.Method != "" {
+			fmt.Printf("      Method:      %s\n", finding.Method)
+		}
+		fmt.Printf("      Evidence:    %s\n", finding.Evidence)
+		fmt.Printf("      Remediation: %s\n", finding.Remediation)
+	}
+}
+
+// isStandardService reports whether a method belongs to a built-in gRPC service
+func isStandardService(fullMethod string) bool {
+	return strings.HasPrefix(fullMethod, "grpc.health.") || strings.HasPrefix(fullMethod, "grpc.reflection.")
+}
+
+// splitFullMethod splits "Service/Method" into its two parts
+func splitFullMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if pos := strings.LastIndex(fullMethod, "/"); pos >= 0 {
+		return fullMethod[:pos], fullMethod[pos+1:]
+	}
+	return fullMethod, ""
+}
+
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	return text[:max] + "..."
+}
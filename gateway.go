@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// GatewayRoute is a REST route that reaches a gRPC method through
+// grpc-gateway or Envoy JSON transcoding
+type GatewayRoute struct {
+	Method     string `json:"method"`
+	HTTPMethod string `json:"http_method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status,omitempty"`
+	Source     string `json:"source"` // "probe" or "openapi"
+	Evidence   string `json:"evidence,omitempty"`
+}
+
+// GatewayResult collects transcoded routes and the OpenAPI document, if any
+type GatewayResult struct {
+	BaseURL string         `json:"base_url"`
+	OpenAPI string         `json:"openapi,omitempty"`
+	Routes  []GatewayRoute `json:"routes,omitempty"`
+}
+
+// Paths where grpc-gateway deployments commonly serve their openapiv2 output
+var openAPIPaths = []string{
+	"/swagger.json",
+	"/openapi.json",
+	"/openapiv2.json",
+	"/apidocs.swagger.json",
+	"/api.swagger.json",
+	"/service.swagger.json",
+	"/swagger/swagger.json",
+	"/swagger/doc.json",
+	"/swagger/v1/swagger.json",
+	"/api/swagger.json",
+	"/v1/swagger.json",
+	"/docs/swagger.json",
+	"/v2/api-docs",
+	"/v3/api-docs",
+}
+
+// maxGatewayBody caps how much of a REST response is read
+const maxGatewayBody = 1 << 20
+
+type gatewayCandidate struct {
+	httpMethod string
+	path       string
+}
+
+// runGateway imports a served OpenAPI document and probes likely transcoded
+// routes for every known method
+func (s *Scanner) runGateway() {
+	baseURL := strings.TrimRight(s.gatewayURL, "/")
+	if baseURL == "" {
+		baseURL = httpBaseURL(s.target, s.useTLS)
+	}
+	result := &GatewayResult{BaseURL: baseURL}
+	client := newHTTPClient()
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	defer client.CloseIdleConnections()
+
+	fmt.Printf("\n[+] Probing REST gateway routes at %s...\n", baseURL)
+
+	seen := make(map[string]bool)
+	if specURL, spec := s.fetchOpenAPI(client, baseURL); spec != nil {
+		result.OpenAPI = specURL
+		ops := openAPIOperations(spec)
+		fmt.Printf("[!] OpenAPI document served at %s (%d operations)\n", specURL, len(ops))
+		for _, op := range ops {
+			service := s.resolveServiceName(op.Service)
+			s.addService(service, "openapi")
+			s.addMethod(service, op.Method)
+			seen[op.HTTPMethod+" "+op.Path] = true
+			result.Routes = append(result.Routes, GatewayRoute{
+				Method:     service + "/" + op.Method,
+				HTTPMethod: op.HTTPMethod,
+				Path:       op.Path,
+				Source:     "openapi",
+			})
+		}
+		s.addFinding(Finding{
+			ID:          "openapi-spec-exposed",
+			Title:       "OpenAPI document for the gRPC API is served",
+			Severity:    "medium",
+			Evidence:    fmt.Sprintf("%s lists %d operations", specURL, len(ops)),
+			Remediation: "Do not publish the generated openapiv2 document on production gateways.",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	methods := s.callableMethods(ctx)
+	cancel()
+
+	probed := 0
+	for _, fullMethod := range methods {
+		for _, candidate := range gatewayCandidates(fullMethod) {
+			key := candidate.httpMethod + " " + candidate.path
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			probed++
+
+			status, evidence, ok := s.probeGatewayRoute(client, baseURL, candidate)
+			if !ok {
+				continue
+			}
+			fmt.Printf("[+] %s reachable as %s %s (%s)\n", fullMethod, candidate.httpMethod, candidate.path, evidence)
+			result.Routes = append(result.Routes, GatewayRoute{
+				Method:     fullMethod,
+				HTTPMethod: candidate.httpMethod,
+				Path:       candidate.path,
+				Status:     status,
+				Source:     "probe",
+				Evidence:   evidence,
+			})
+		}
+	}
+
+	if s.verbose {
+		fmt.Printf("[+] Probed %d gateway routes\n", probed)
+	}
+
+	var probedRoutes []string
+	for _, route := range result.Routes {
+		if route.Source == "probe" {
+			probedRoutes = append(probedRoutes, route.HTTPMethod+" "+route.Path)
+		}
+	}
+	if len(probedRoutes) > 0 {
+		s.addFinding(Finding{
+			ID:          "grpc-gateway-routes",
+			Title:       "gRPC methods are reachable through a REST gateway",
+			Severity:    "low",
+			Evidence:    truncate(strings.Join(probedRoutes, ", "), 300),
+			Remediation: "Apply the same authentication and authorization to transcoded REST routes as to the gRPC methods.",
+		})
+	}
+
+	s.resultMutex.Lock()
+	s.result.Gateway = result
+	s.resultMutex.Unlock()
+}
+
+// fetchOpenAPI looks for an OpenAPI document at the common paths
+func (s *Scanner) fetchOpenAPI(client *http.Client, baseURL string) (string, *openAPISpec) {
+	for _, path := range openAPIPaths {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		status, _, body, err := gatewayRequest(ctx, client, http.MethodGet, baseURL+path)
+		cancel()
+		if err != nil || status != http.StatusOK {
+			continue
+		}
+		if spec, err := parseOpenAPI(body); err == nil {
+			return baseURL + path, spec
+		}
+	}
+	return "", nil
+}
+
+// resolveServiceName maps a short service name from OpenAPI tags or
+// operationIds to a discovered fully-qualified service
+func (s *Scanner) resolveServiceName(name string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+	for _, service := range s.servicesSnapshot() {
+		if strings.HasSuffix(service, "."+name) {
+			return service
+		}
+	}
+	return name
+}
+
+// gatewayCandidates lists the REST routes a method is likely transcoded to:
+// Envoy's auto-mapped POST /package.Service/Method and grpc-gateway style
+// /v1/{resource} routes derived from the method verb
+func gatewayCandidates(fullMethod string) []gatewayCandidate {
+	service, method := splitFullMethod(fullMethod)
+	candidates := []gatewayCandidate{{http.MethodPost, "/" + fullMethod}}
+
+	short := service
+	if pos := strings.LastIndex(short, "."); pos >= 0 {
+		short = short[pos+1:]
+	}
+	resource := pluralize(strings.ToLower(strings.TrimSuffix(short, "Service")))
+
+	verb, noun := splitMethodVerb(method)
+	collection := resource
+	if noun != "" {
+		collection = pluralize(strings.ToLower(noun))
+	}
+
+	switch verb {
+	case "Get":
+		candidates = append(candidates,
+			gatewayCandidate{http.MethodGet, "/v1/" + collection + "/1"},
+			gatewayCandidate{http.MethodGet, "/v1/" + collection})
+	case "List":
+		candidates = append(candidates, gatewayCandidate{http.MethodGet, "/v1/" + collection})
+	case "Create":
+		candidates = append(candidates, gatewayCandidate{http.MethodPost, "/v1/" + collection})
+	case "Update":
+		candidates = append(candidates,
+			gatewayCandidate{http.MethodPatch, "/v1/" + collection + "/1"},
+			gatewayCandidate{http.MethodPut, "/v1/" + collection + "/1"})
+	case "Delete":
+		candidates = append(candidates, gatewayCandidate{http.MethodDelete, "/v1/" + collection + "/1"})
+	default:
+		candidates = append(candidates,
+			gatewayCandidate{http.MethodPost, "/v1/" + resource + ":" + lowerFirst(method)},
+			gatewayCandidate{http.MethodPost, "/v1/" + resource + "/" + kebabCase(method)},
+			gatewayCandidate{http.MethodPost, "/v1/" + kebabCase(method)})
+	}
+	return candidates
+}
+
+// splitMethodVerb splits a standard AIP method name such as GetUser into
+// its verb and noun
+func splitMethodVerb(method string) (string, string) {
+	for _, verb := range []string{"Get", "List", "Create", "Update", "Delete"} {
+		if rest := strings.TrimPrefix(method, verb); rest != method && (rest == "" || unicode.IsUpper(rune(rest[0]))) {
+			return verb, rest
+		}
+	}
+	return "", ""
+}
+
+func pluralize(word string) string {
+	switch {
+	case word == "" || strings.HasSuffix(word, "s"):
+		return word
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !strings.ContainsRune("aeiou", rune(word[len(word)-2])):
+		return word[:len(word)-1] + "ies"
+	}
+	return word + "s"
+}
+
+func lowerFirst(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToLower(word[:1]) + word[1:]
+}
+
+func kebabCase(word string) string {
+	var b strings.Builder
+	for i, r := range word {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// probeGatewayRoute sends one anonymous REST request and decides whether it
+// reached a gRPC backend
+func (s *Scanner) probeGatewayRoute(client *http.Client, baseURL string, candidate gatewayCandidate) (int, string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	status, header, body, err := gatewayRequest(ctx, client, candidate.httpMethod, baseURL+candidate.path)
+	if err != nil {
+		return 0, "", false
+	}
+	evidence, ok := gatewayEvidence(status, header, body)
+	return status, evidence, ok
+}
+
+func gatewayRequest(ctx context.Context, client *http.Client, method, url string) (int, http.Header, []byte, error) {
+	var body io.Reader
+	if method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch {
+		body = bytes.NewReader([]byte("{}"))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxGatewayBody))
+	return resp.StatusCode, resp.Header, data, err
+}
+
+// gatewayEvidence decides whether a REST response came from a transcoded
+// gRPC call. grpc-gateway forwards metadata as Grpc-Metadata-* headers and
+// answers errors with {"code": N, "message": ...}; its router answers
+// unmatched paths with code 5 and the bare message "Not Found".
+func gatewayEvidence(status int, header http.Header, body []byte) (string, bool) {
+	if code := header.Get("Grpc-Status"); code != "" {
+		return "grpc-status " + code, true
+	}
+	for name := range header {
+		if strings.HasPrefix(name, "Grpc-Metadata-") {
+			return fmt.Sprintf("HTTP %d with %s header", status, name), true
+		}
+	}
+
+	if !strings.Contains(header.Get("Content-Type"), "json") {
+		return "", false
+	}
+
+	var gwErr struct {
+		Code    *int   `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &gwErr) == nil && gwErr.Code != nil && status >= 400 {
+		if status == http.StatusNotFound && gwErr.Message == http.StatusText(http.StatusNotFound) {
+			return "", false
+		}
+		if status == http.StatusMethodNotAllowed {
+			return "", false
+		}
+		return fmt.Sprintf("HTTP %d, gRPC code %d: %s", status, *gwErr.Code, truncate(gwErr.Message, 80)), true
+	}
+
+	if status >= 200 && status < 300 {
+		return fmt.Sprintf("HTTP %d JSON response", status), true
+	}
+	return "", false
+}
+
+// printGatewayRoutes lists REST routes that reach gRPC methods
+func (s *Scanner) printGatewayRoutes() {
+	gw := s.result.Gateway
+	if gw == nil || len(gw.Routes) == 0 {
+		return
+	}
+
+	fmt.Printf("\nREST Gateway Routes (%s):\n", gw.BaseURL)
+	for _, route := range gw.Routes {
+		line := fmt.Sprintf("   %-6s %-40s -> %s", route.HTTPMethod, route.Path, route.Method)
+		if route.Source == "openapi" {
+			line += "  [openapi]"
+		} else if s.verbose && route.Evidence != "" {
+			line += "  (" + route.Evidence + ")"
+		}
+		fmt.Println(line)
+	}
+}
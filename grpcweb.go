@@ -0,0 +1,602 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// transportErrorPrefix marks errors raised by the HTTP transports themselves,
+// as opposed to statuses sent by the server
+const transportErrorPrefix = "transport: "
+
+// gRPC-Web frame flags
+const (
+	webDataFrame    = 0x00
+	webTrailerFrame = 0x80
+)
+
+// grpcWebConn sends calls as gRPC-Web (application/grpc-web or
+// application/grpc-web-text) over HTTP/1.1 or HTTP/2
+type grpcWebConn struct {
+	baseURL string
+	text    bool
+	client  *http.Client
+}
+
+func newGRPCWebConn(target string, text, useTLS bool) *grpcWebConn {
+	return &grpcWebConn{
+		baseURL: httpBaseURL(target, useTLS),
+		text:    text,
+		client:  newHTTPClient(),
+	}
+}
+
+// httpBaseURL returns the URL prefix calls to target are posted under
+func httpBaseURL(target string, useTLS bool) string {
+	if useTLS {
+		return "https://" + target
+	}
+	return "http://" + target
+}
+
+// newHTTPClient returns the client used by the HTTP-based transports
+func newHTTPClient() *http.Client {
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+}
+
+func (c *grpcWebConn) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+// webCallOptions are the grpc.CallOptions the HTTP transports understand
+type webCallOptions struct {
+	header  *metadata.MD
+	trailer *metadata.MD
+	codec   encoding.Codec
+	subtype string
+}
+
+func parseWebCallOptions(opts []grpc.CallOption) webCallOptions {
+	var o webCallOptions
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case grpc.HeaderCallOption:
+			o.header = opt.HeaderAddr
+		case grpc.TrailerCallOption:
+			o.trailer = opt.TrailerAddr
+		case grpc.ForceCodecCallOption:
+			o.codec = opt.Codec
+		case grpc.ContentSubtypeCallOption:
+			o.subtype = opt.ContentSubtype
+		}
+	}
+	return o
+}
+
+func (o webCallOptions) marshal(v interface{}) ([]byte, error) {
+	if o.codec != nil {
+		return o.codec.Marshal(v)
+	}
+	if v == nil {
+		return nil, nil
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+	return proto.Marshal(msg)
+}
+
+func (o webCallOptions) unmarshal(data []byte, v interface{}) error {
+	if o.codec != nil {
+		return o.codec.Unmarshal(data, v)
+	}
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// capture copies response headers and trailers to the caller's call options
+func (o webCallOptions) capture(header, trailer metadata.MD) {
+	if o.header != nil && header != nil {
+		*o.header = header
+	}
+	if o.trailer != nil && trailer != nil {
+		*o.trailer = trailer
+	}
+}
+
+func (c *grpcWebConn) contentType(subtype string) string {
+	contentType := "application/grpc-web"
+	if c.text {
+		contentType += "-text"
+	}
+	if subtype != "" {
+		contentType += "+" + subtype
+	}
+	return contentType
+}
+
+// Invoke performs a unary call
+func (c *grpcWebConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	o := parseWebCallOptions(opts)
+	payload, err := o.marshal(args)
+	if err != nil {
+		return status.Errorf(codes.Internal, "grpc: error while marshaling: %v", err)
+	}
+
+	resp, err := c.send(ctx, method, [][]byte{payload}, o)
+	if resp != nil {
+		defer resp.close()
+	}
+	if err != nil {
+		return err
+	}
+
+	received := false
+	for {
+		data, err := resp.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			o.capture(resp.header, resp.trailer)
+			return err
+		}
+		if received {
+			// Like grpc-go, a second message on a unary call is an error,
+			// which also keeps server streams from holding the call open
+			o.capture(resp.header, resp.trailer)
+			return status.Errorf(codes.Internal, "cardinality violation: expected <EOF> for non server-streaming RPCs, but received another message")
+		}
+		received = true
+		if err := o.unmarshal(data, reply); err != nil {
+			o.capture(resp.header, resp.trailer)
+			return status.Errorf(codes.Internal, "grpc: failed to unmarshal the received message: %v", err)
+		}
+	}
+
+	o.capture(resp.header, resp.trailer)
+	return resp.err
+}
+
+// NewStream opens a stream. gRPC-Web has no full-duplex streaming, so sent
+// messages are buffered and posted as one request when the caller first
+// receives; a send after receiving starts a new request.
+func (c *grpcWebConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return &httpStream{ctx: ctx, conn: c, method: method, opts: parseWebCallOptions(opts)}, nil
+}
+
+// send posts framed messages and opens the response
+func (c *grpcWebConn) send(ctx context.Context, method string, payloads [][]byte, o webCallOptions) (*webResponse, error) {
+	var body bytes.Buffer
+	for _, payload := range payloads {
+		body.Write(webFrame(webDataFrame, payload))
+	}
+	data := body.Bytes()
+	if c.text {
+		data = []byte(base64.StdEncoding.EncodeToString(data))
+	}
+
+	if !strings.HasPrefix(method, "/") {
+		method = "/" + method
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, bytes.NewReader(data))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%sinvalid request: %v", transportErrorPrefix, err)
+	}
+
+	contentType := c.contentType(o.subtype)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", contentType)
+	req.Header.Set("X-Grpc-Web", "1")
+	req.Header.Set("X-User-Agent", "grpc-web-scanner")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", fmt.Sprintf("%dm", time.Until(deadline).Milliseconds()))
+	}
+	setOutgoingHeaders(ctx, req.Header)
+
+	httpResp, err := c.client.Do(req)
+	if err != nil {
+		return nil, transportError(ctx, err)
+	}
+
+	resp, err := openWebResponse(ctx, httpResp)
+	if err != nil {
+		o.capture(httpHeaderMD(httpResp.Header), nil)
+	}
+	return resp, err
+}
+
+// setOutgoingHeaders copies outgoing gRPC metadata to HTTP request headers
+func setOutgoingHeaders(ctx context.Context, header http.Header) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for key, values := range md {
+		for _, value := range values {
+			if strings.HasSuffix(key, "-bin") {
+				value = base64.StdEncoding.EncodeToString([]byte(value))
+			}
+			header.Add(key, value)
+		}
+	}
+}
+
+// transportError converts an HTTP client error into a status
+func transportError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+	return status.Errorf(codes.Unavailable, "%s%v", transportErrorPrefix, err)
+}
+
+func webFrame(flag byte, payload []byte) []byte {
+	frame := make([]byte, 5+len(payload))
+	frame[0] = flag
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(payload)))
+	copy(frame[5:], payload)
+	return frame
+}
+
+// webResponse reads frames from a gRPC-Web or Connect streaming response
+// body. A frame with endFlag set ends the stream and parseEnd turns its
+// payload into trailers carrying the final grpc-status.
+type webResponse struct {
+	ctx      context.Context
+	body     io.ReadCloser
+	reader   *bufio.Reader
+	httpRsp  *http.Response
+	header   metadata.MD
+	trailer  metadata.MD
+	endFlag  byte
+	parseEnd func([]byte) metadata.MD
+	done     bool
+	err      error
+}
+
+// openWebResponse validates the HTTP response and handles trailers-only
+// responses, where the status arrives in the HTTP headers
+func openWebResponse(ctx context.Context, httpResp *http.Response) (*webResponse, error) {
+	resp := &webResponse{
+		ctx:      ctx,
+		body:     httpResp.Body,
+		httpRsp:  httpResp,
+		header:   httpHeaderMD(httpResp.Header),
+		trailer:  metadata.MD{},
+		endFlag:  webTrailerFrame,
+		parseEnd: parseWebTrailers,
+	}
+
+	if httpResp.Header.Get("Grpc-Status") != "" {
+		resp.done = true
+		resp.err = statusFromMD(resp.header)
+		resp.close()
+		return resp, resp.err
+	}
+
+	contentType := httpResp.Header.Get("Content-Type")
+	if httpResp.StatusCode != http.StatusOK {
+		resp.close()
+		return resp, httpStatusError(httpResp)
+	}
+	if !strings.HasPrefix(contentType, "application/grpc-web") {
+		resp.close()
+		return resp, status.Errorf(codes.Unknown, "transport: received unexpected content-type %q", contentType)
+	}
+
+	var body io.Reader = httpResp.Body
+	if strings.HasPrefix(contentType, "application/grpc-web-text") {
+		body = &webTextReader{r: bufio.NewReader(httpResp.Body)}
+	}
+	resp.reader = bufio.NewReader(body)
+	return resp, nil
+}
+
+// HTTP status to gRPC code conversion, as done by grpc-go for native calls
+var httpStatusCodes = map[int]codes.Code{
+	http.StatusBadRequest:         codes.Internal,
+	http.StatusUnauthorized:       codes.Unauthenticated,
+	http.StatusForbidden:          codes.PermissionDenied,
+	http.StatusNotFound:           codes.Unimplemented,
+	http.StatusTooManyRequests:    codes.Unavailable,
+	http.StatusBadGateway:         codes.Unavailable,
+	http.StatusServiceUnavailable: codes.Unavailable,
+	http.StatusGatewayTimeout:     codes.Unavailable,
+}
+
+// httpStatusError converts a non-200 response without a gRPC status into an
+// error, mapping the HTTP status the way grpc-go does
+func httpStatusError(httpResp *http.Response) error {
+	code, ok := httpStatusCodes[httpResp.StatusCode]
+	if !ok {
+		code = codes.Unknown
+	}
+	return status.Errorf(code, "unexpected HTTP status code received from server: %d (%s); transport: received unexpected content-type %q",
+		httpResp.StatusCode, http.StatusText(httpResp.StatusCode), httpResp.Header.Get("Content-Type"))
+}
+
+// next returns the next data frame, or io.EOF once the trailers were read
+func (r *webResponse) next() ([]byte, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+
+	for {
+		var prefix [5]byte
+		if _, err := io.ReadFull(r.reader, prefix[:]); err != nil {
+			if err == io.EOF {
+				return nil, r.finish(nil)
+			}
+			r.done = true
+			if ctxErr := r.ctx.Err(); ctxErr != nil {
+				r.err = status.FromContextError(ctxErr).Err()
+			} else {
+				r.err = status.Errorf(codes.Internal, "%sreading response: %v", transportErrorPrefix, err)
+			}
+			return nil, r.err
+		}
+
+		// The length comes from the server; check it before allocating so a
+		// hostile target cannot make the scanner allocate gigabytes
+		length := binary.BigEndian.Uint32(prefix[1:5])
+		if length > maxConnectBody {
+			r.done = true
+			r.err = status.Errorf(codes.ResourceExhausted, "%sframe of %d bytes exceeds the %d byte limit", transportErrorPrefix, length, maxConnectBody)
+			r.close()
+			return nil, r.err
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r.reader, payload); err != nil {
+			r.done = true
+			r.err = status.Errorf(codes.Internal, "%struncated frame: %v", transportErrorPrefix, err)
+			return nil, r.err
+		}
+
+		if prefix[0]&r.endFlag != 0 {
+			return nil, r.finish(r.parseEnd(payload))
+		}
+		return payload, nil
+	}
+}
+
+// finish records the final status from a trailer frame or HTTP trailers
+func (r *webResponse) finish(trailer metadata.MD) error {
+	r.done = true
+	if trailer == nil {
+		trailer = httpHeaderMD(r.httpRsp.Trailer)
+	}
+	if trailer.Get("grpc-status") == nil {
+		r.err = status.Error(codes.Internal, "server closed the stream without sending trailers")
+	} else {
+		r.err = statusFromMD(trailer)
+	}
+	r.trailer = stripStatusMD(trailer)
+	r.close()
+	return io.EOF
+}
+
+func (r *webResponse) close() {
+	if r.body != nil {
+		// Only drain a finished response for connection reuse; an open
+		// server stream would block until the deadline
+		if r.done {
+			io.Copy(io.Discard, io.LimitReader(r.body, 1<<16))
+		}
+		r.body.Close()
+		r.body = nil
+	}
+}
+
+// parseWebTrailers parses the "key: value\r\n" block of a trailer frame
+func parseWebTrailers(payload []byte) metadata.MD {
+	md := metadata.MD{}
+	for _, line := range strings.Split(string(payload), "\r\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		md.Append(key, strings.TrimSpace(value))
+	}
+	return md
+}
+
+// httpHeaderMD converts HTTP headers to lowercase metadata
+func httpHeaderMD(header http.Header) metadata.MD {
+	md := metadata.MD{}
+	for key, values := range header {
+		md.Append(strings.ToLower(key), values...)
+	}
+	return md
+}
+
+// stripStatusMD removes the status fields, which surface through the error
+func stripStatusMD(md metadata.MD) metadata.MD {
+	stripped := md.Copy()
+	for _, key := range []string{"grpc-status", "grpc-message", "grpc-status-details-bin"} {
+		delete(stripped, key)
+	}
+	return stripped
+}
+
+// statusFromMD builds the call status from grpc-status, grpc-message and
+// grpc-status-details-bin
+func statusFromMD(md metadata.MD) error {
+	code := codes.Unknown
+	if values := md.Get("grpc-status"); len(values) > 0 {
+		if n, err := strconv.Atoi(strings.TrimSpace(values[0])); err == nil {
+			code = codes.Code(n)
+		}
+	}
+	if code == codes.OK {
+		return nil
+	}
+
+	message := ""
+	if values := md.Get("grpc-message"); len(values) > 0 {
+		message = values[0]
+		if decoded, err := url.PathUnescape(message); err == nil {
+			message = decoded
+		}
+	}
+
+	if values := md.Get("grpc-status-details-bin"); len(values) > 0 {
+		raw, err := base64.StdEncoding.DecodeString(values[0])
+		if err != nil {
+			raw, err = base64.RawStdEncoding.DecodeString(values[0])
+		}
+		if err == nil {
+			st := &spb.Status{}
+			if proto.Unmarshal(raw, st) == nil && codes.Code(st.GetCode()) == code {
+				return status.FromProto(st).Err()
+			}
+		}
+	}
+
+	return status.Error(code, message)
+}
+
+// webTextReader decodes a grpc-web-text body. Each chunk is padded base64, so
+// the body is decoded one 4-character quantum at a time.
+type webTextReader struct {
+	r   *bufio.Reader
+	buf []byte
+}
+
+func (t *webTextReader) Read(p []byte) (int, error) {
+	for len(t.buf) == 0 {
+		quantum := make([]byte, 0, 4)
+		for len(quantum) < 4 {
+			b, err := t.r.ReadByte()
+			if err != nil {
+				if err == io.EOF && len(quantum) > 0 {
+					err = io.ErrUnexpectedEOF
+				}
+				return 0, err
+			}
+			if b == '\r' || b == '\n' || b == ' ' || b == '\t' {
+				continue
+			}
+			quantum = append(quantum, b)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(quantum))
+		if err != nil {
+			return 0, err
+		}
+		t.buf = decoded
+	}
+
+	n := copy(p, t.buf)
+	t.buf = t.buf[n:]
+	return n, nil
+}
+
+// streamSender posts a batch of messages as one streaming request
+type streamSender interface {
+	send(ctx context.Context, method string, payloads [][]byte, o webCallOptions) (*webResponse, error)
+}
+
+// httpStream is a client stream over an HTTP-based transport
+type httpStream struct {
+	ctx     context.Context
+	conn    streamSender
+	method  string
+	opts    webCallOptions
+	pending [][]byte
+	resp    *webResponse
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *httpStream) Header() (metadata.MD, error) {
+	return s.header, nil
+}
+
+func (s *httpStream) Trailer() metadata.MD {
+	return s.trailer
+}
+
+func (s *httpStream) CloseSend() error {
+	return nil
+}
+
+func (s *httpStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *httpStream) SendMsg(m interface{}) error {
+	payload, err := s.opts.marshal(m)
+	if err != nil {
+		return status.Errorf(codes.Internal, "grpc: error while marshaling: %v", err)
+	}
+	s.pending = append(s.pending, payload)
+	return nil
+}
+
+func (s *httpStream) RecvMsg(m interface{}) error {
+	for {
+		// A new send after a response means a new request; finish the old one
+		if s.resp != nil && !s.resp.done && len(s.pending) > 0 {
+			for {
+				if _, err := s.resp.next(); err != nil {
+					break
+				}
+			}
+		}
+
+		if s.resp == nil || (s.resp.done && len(s.pending) > 0) {
+			resp, err := s.conn.send(s.ctx, s.method, s.pending, s.opts)
+			s.pending = nil
+			if resp != nil {
+				s.header = resp.header
+				s.trailer = resp.trailer
+			}
+			if err != nil {
+				return err
+			}
+			s.resp = resp
+		}
+
+		data, err := s.resp.next()
+		if errors.Is(err, io.EOF) {
+			s.trailer = s.resp.trailer
+			s.opts.capture(s.header, s.trailer)
+			if s.resp.err != nil {
+				return s.resp.err
+			}
+			if len(s.pending) > 0 {
+				continue
+			}
+			return io.EOF
+		}
+		if err != nil {
+			return err
+		}
+		return s.opts.unmarshal(data, m)
+	}
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// maxHarvestRounds bounds how often a method is re-sent with new fields
+	maxHarvestRounds = 10
+	// maxRawFieldNumber is the highest field number tried in raw mode
+	maxRawFieldNumber = 20
+)
+
+// RequestSkeleton is a best-effort request message built from BadRequest hints
+type RequestSkeleton struct {
+	Method       string          `json:"method"`
+	Mode         string          `json:"mode"` // "descriptor" or "raw"
+	Fields       []SkeletonField `json:"fields"`
+	JSON         json.RawMessage `json:"json,omitempty"`
+	Rounds       int             `json:"rounds"`
+	FinalCode    string          `json:"final_code"`
+	FinalMessage string          `json:"final_message,omitempty"`
+}
+
+// SkeletonField is one harvested request field
+type SkeletonField struct {
+	Name   string `json:"name"`
+	Number int    `json:"number,omitempty"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+}
+
+// rawCodec sends pre-encoded protobuf bytes and discards the reply
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	data, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("rawCodec: unexpected type %T", v)
+	}
+	return data, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	return nil
+}
+
+func (rawCodec) Name() string {
+	return "proto"
+}
+
+var (
+	intFieldPattern  = regexp.MustCompile(`(?i)(count|limit|size|page|age|amount|quantity|port|num|number|offset|year|total|version|expires)`)
+	boolFieldPattern = regexp.MustCompile(`(?i)^(is_|has_|is[A-Z]|has[A-Z])|(enabled|active|flag|verified|deleted)$`)
+	intDescPattern   = regexp.MustCompile(`(?i)(integer|number|numeric|positive|negative|greater than|less than|between \d)`)
+	boolDescPattern  = regexp.MustCompile(`(?i)\b(boolean|true|false)\b`)
+	fieldIndexSuffix = regexp.MustCompile(`\[\d*\]`)
+)
+
+// guessFieldValue picks a wire type and placeholder value from a field name
+// and the violation description that mentioned it
+func guessFieldValue(name, description string) (string, string) {
+	lower := strings.ToLower(name)
+	switch {
+	case boolFieldPattern.MatchString(name) || boolDescPattern.MatchString(description):
+		return "bool", "true"
+	case intDescPattern.MatchString(description) || (intFieldPattern.MatchString(lower) && !strings.HasSuffix(lower, "id")):
+		return "int", "1"
+	case strings.Contains(lower, "email"):
+		return "string", "user@example.com"
+	case strings.Contains(lower, "url") || strings.Contains(lower, "uri"):
+		return "string", "https://example.com"
+	case strings.HasSuffix(lower, "_at") || strings.Contains(lower, "time") || strings.Contains(lower, "date"):
+		return "string", "2024-01-01T00:00:00Z"
+	case strings.Contains(lower, "password") || strings.Contains(lower, "secret"):
+		return "string", "Passw0rd!"
+	case strings.HasSuffix(lower, "id"):
+		return "string", "1"
+	}
+	return "string", "test"
+}
+
+// badRequestFields returns the field names of a status' BadRequest violations,
+// mapped to their descriptions
+func badRequestFields(st *status.Status) map[string]string {
+	fields := make(map[string]string)
+	for _, detail := range st.Details() {
+		if br, ok := detail.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				if v.GetField() != "" {
+					fields[v.GetField()] = v.GetDescription()
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// callableMethods lists confirmed methods plus, when reflection is enabled,
+// the unary methods of every reflected service
+func (s *Scanner) callableMethods(ctx context.Context) []string {
+	return s.discoveredMethods(ctx, true)
+}
+
+// discoveredMethods lists confirmed methods plus, when reflection is
+// enabled, the methods of every reflected service; unaryOnly leaves out
+// streaming methods
+func (s *Scanner) discoveredMethods(ctx context.Context, unaryOnly bool) []string {
+	seen := make(map[string]bool)
+	for _, fullMethod := range s.confirmedMethods() {
+		seen[fullMethod] = true
+	}
+
+	if s.result.ReflectionEnabled {
+		for _, service := range s.servicesSnapshot() {
+			if isStandardService(service + "/") {
+				continue
+			}
+			sd, err := s.serviceDescriptor(ctx, service)
+			if err != nil {
+				continue
+			}
+			for i := 0; i < sd.Methods().Len(); i++ {
+				md := sd.Methods().Get(i)
+				if !unaryOnly || (!md.IsStreamingClient() && !md.IsStreamingServer()) {
+					seen[service+"/"+string(md.Name())] = true
+				}
+			}
+		}
+	}
+
+	methods := make([]string, 0, len(seen))
+	for fullMethod := range seen {
+		methods = append(methods, fullMethod)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// serviceDescriptor resolves a service through server reflection
+func (s *Scanner) serviceDescriptor(ctx context.Context, service string) (protoreflect.ServiceDescriptor, error) {
+	if s.descriptors != nil {
+		if desc, err := s.descriptors.FindDescriptorByName(protoreflect.FullName(service)); err == nil {
+			if sd, ok := desc.(protoreflect.ServiceDescriptor); ok {
+				return sd, nil
+			}
+		}
+	}
+
+	client := grpc_reflection_v1alpha.NewServerReflectionClient(s.recording())
+	stream, err := client.ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.CloseSend()
+
+	protos := make(map[string]*descriptorpb.FileDescriptorProto)
+	fetch := func(req *grpc_reflection_v1alpha.ServerReflectionRequest) error {
+		if err := stream.Send(req); err != nil {
+			return err
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if errResp := resp.GetErrorResponse(); errResp != nil {
+			return fmt.Errorf("reflection error: %s", errResp.GetErrorMessage())
+		}
+		for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fdp := &descriptorpb.FileDescriptorProto{}
+			if err := proto.Unmarshal(raw, fdp); err != nil {
+				return err
+			}
+			protos[fdp.GetName()] = fdp
+		}
+		return nil
+	}
+
+	if err := fetch(&grpc_reflection_v1alpha.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1alpha.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: service},
+	}); err != nil {
+		return nil, err
+	}
+
+	// Fetch any dependencies the server did not send along
+	for {
+		missing := ""
+		for _, fdp := range protos {
+			for _, dep := range fdp.GetDependency() {
+				if _, ok := protos[dep]; !ok {
+					missing = dep
+					break
+				}
+			}
+		}
+		if missing == "" {
+			break
+		}
+		if err := fetch(&grpc_reflection_v1alpha.ServerReflectionRequest{
+			MessageRequest: &grpc_reflection_v1alpha.ServerReflectionRequest_FileByFilename{FileByFilename: missing},
+		}); err != nil {
+			return nil, err
+		}
+		if _, ok := protos[missing]; !ok {
+			return nil, fmt.Errorf("reflection did not return %s", missing)
+		}
+	}
+
+	if s.descriptors == nil {
+		s.descriptors = new(protoregistry.Files)
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fdp := range protos {
+		set.File = append(set.File, fdp)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		if _, err := s.descriptors.FindFileByPath(fd.Path()); err != nil {
+			s.descriptors.RegisterFile(fd)
+		}
+		return true
+	})
+
+	desc, err := s.descriptors.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, err
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", service)
+	}
+	return sd, nil
+}
+
+// runHarvest builds request skeletons for every harvest target
+func (s *Scanner) runHarvest() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	methods := s.callableMethods(ctx)
+	cancel()
+
+	if len(methods) == 0 {
+		fmt.Println("\n[-] Harvest skipped: no methods to probe")
+		return
+	}
+
+	fmt.Printf("\n[+] Harvesting request fields from BadRequest violations on %d methods...\n", len(methods))
+	for _, fullMethod := range methods {
+		skeleton := s.harvestMethod(fullMethod)
+		if skeleton == nil || len(skeleton.Fields) == 0 {
+			continue
+		}
+
+		fmt.Printf("[+] %s: %d fields harvested (%s mode, final status %s)\n",
+			fullMethod, len(skeleton.Fields), skeleton.Mode, skeleton.FinalCode)
+		s.resultMutex.Lock()
+		s.result.RequestSkeletons = append(s.result.RequestSkeletons, *skeleton)
+		s.resultMutex.Unlock()
+	}
+}
+
+// harvestMethod re-sends a method with harvested fields until it stops
+// returning InvalidArgument or no new fields are reported
+func (s *Scanner) harvestMethod(fullMethod string) *RequestSkeleton {
+	service, method := splitFullMethod(fullMethod)
+
+	var input protoreflect.MessageDescriptor
+	if s.result.ReflectionEnabled {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		if sd, err := s.serviceDescriptor(ctx, service); err == nil {
+			if md := sd.Methods().ByName(protoreflect.Name(method)); md != nil {
+				input = md.Input()
+			}
+		}
+		cancel()
+	}
+
+	skeleton := &RequestSkeleton{Method: fullMethod, Mode: "raw"}
+	var msg *dynamicpb.Message
+	if input != nil {
+		skeleton.Mode = "descriptor"
+		msg = dynamicpb.NewMessage(input)
+	}
+
+	encode := func(extra *SkeletonField) ([]byte, error) {
+		if msg != nil {
+			return proto.Marshal(msg)
+		}
+		fields := skeleton.Fields
+		if extra != nil {
+			fields = append(append([]SkeletonField{}, fields...), *extra)
+		}
+		return encodeRawFields(fields), nil
+	}
+
+	st := s.sendRaw(fullMethod, encode, nil)
+	for skeleton.Rounds < maxHarvestRounds && st.Code() == codes.InvalidArgument {
+		violations := badRequestFields(st)
+		names := make([]string, 0, len(violations))
+		for name := range violations {
+			if !skeleton.hasField(name) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			break
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			kind, value := guessFieldValue(name, violations[name])
+			field := SkeletonField{Name: name, Type: kind, Value: value}
+
+			if msg != nil {
+				if kind, value, err := setFieldPath(msg, name, field); err == nil {
+					field.Type, field.Value = kind, value
+				} else if s.verbose {
+					fmt.Printf("   [-] %s: cannot set %s: %v\n", fullMethod, name, err)
+				}
+			} else if number := s.findRawFieldNumber(fullMethod, name, &field, encode); number > 0 {
+				field.Number = number
+			} else {
+				continue
+			}
+			skeleton.Fields = append(skeleton.Fields, field)
+		}
+
+		skeleton.Rounds++
+		st = s.sendRaw(fullMethod, encode, nil)
+	}
+
+	skeleton.FinalCode = st.Code().String()
+	skeleton.FinalMessage = st.Message()
+	if msg != nil {
+		if data, err := protojson.Marshal(msg); err == nil {
+			skeleton.JSON = data
+		}
+	}
+	return skeleton
+}
+
+func (skeleton *RequestSkeleton) hasField(name string) bool {
+	for _, field := range skeleton.Fields {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sendRaw encodes the current request (plus an optional candidate field) and
+// sends it with the raw codec
+func (s *Scanner) sendRaw(fullMethod string, encode func(*SkeletonField) ([]byte, error), extra *SkeletonField) *status.Status {
+	data, err := encode(extra)
+	if err != nil {
+		return status.Convert(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	var header, trailer metadata.MD
+	err = s.conn.Invoke(ctx, "/"+fullMethod, data, nil, grpc.ForceCodec(rawCodec{}), grpc.Header(&header), grpc.Trailer(&trailer))
+	s.recordErrorMessage("/"+fullMethod, err)
+	s.recordResponseMetadata(header, trailer)
+	return status.Convert(err)
+}
+
+// findRawFieldNumber tries field numbers until the violation for name goes
+// away without the server failing to parse the request
+func (s *Scanner) findRawFieldNumber(fullMethod, name string, field *SkeletonField, encode func(*SkeletonField) ([]byte, error)) int {
+	used := make(map[int]bool)
+	for _, f := range s.skeletonNumbers(encode) {
+		used[f] = true
+	}
+
+	kinds := []string{field.Type}
+	for _, kind := range []string{"string", "int", "bool"} {
+		if kind != field.Type {
+			kinds = append(kinds, kind)
+		}
+	}
+	// Nested paths only tell us the top-level field, which is a message
+	if strings.Contains(name, ".") {
+		kinds = []string{"message"}
+	}
+
+	for number := 1; number <= maxRawFieldNumber; number++ {
+		if used[number] {
+			continue
+		}
+		for _, kind := range kinds {
+			candidate := *field
+			candidate.Number = number
+			candidate.Type = kind
+			if kind != field.Type {
+				_, candidate.Value = defaultRawValue(kind)
+			}
+
+			st := s.sendRaw(fullMethod, encode, &candidate)
+			if st.Code() == codes.Internal || strings.Contains(st.Message(), "unmarshal") {
+				continue
+			}
+			if _, still := badRequestFields(st)[name]; still {
+				continue
+			}
+			*field = candidate
+			return number
+		}
+	}
+	return 0
+}
+
+// skeletonNumbers returns the field numbers already claimed in raw mode
+func (s *Scanner) skeletonNumbers(encode func(*SkeletonField) ([]byte, error)) []int {
+	data, _ := encode(nil)
+	var numbers []int
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			break
+		}
+		data = data[n:]
+		m := protowire.ConsumeFieldValue(num, typ, data)
+		if m < 0 {
+			break
+		}
+		data = data[m:]
+		numbers = append(numbers, int(num))
+	}
+	return numbers
+}
+
+func defaultRawValue(kind string) (string, string) {
+	switch kind {
+	case "int":
+		return kind, "1"
+	case "bool":
+		return kind, "true"
+	case "message":
+		return kind, ""
+	}
+	return kind, "test"
+}
+
+// encodeRawFields encodes harvested raw-mode fields as protobuf wire format
+func encodeRawFields(fields []SkeletonField) []byte {
+	var data []byte
+	for _, field := range fields {
+		num := protowire.Number(field.Number)
+		switch field.Type {
+		case "int":
+			var value uint64 = 1
+			fmt.Sscanf(field.Value, "%d", &value)
+			data = protowire.AppendTag(data, num, protowire.VarintType)
+			data = protowire.AppendVarint(data, value)
+		case "bool":
+			data = protowire.AppendTag(data, num, protowire.VarintType)
+			data = protowire.AppendVarint(data, protowire.EncodeBool(field.Value == "true"))
+		default:
+			data = protowire.AppendTag(data, num, protowire.BytesType)
+			data = protowire.AppendString(data, field.Value)
+		}
+	}
+	return data
+}
+
+// setFieldPath sets a (possibly nested) field on a dynamic message using the
+// descriptor's type, returning the type and value used
+func setFieldPath(msg *dynamicpb.Message, path string, guess SkeletonField) (string, string, error) {
+	current := protoreflect.Message(msg)
+	segments := strings.Split(fieldIndexSuffix.ReplaceAllString(path, ""), ".")
+
+	for i, segment := range segments {
+		fd := findField(current.Descriptor(), segment)
+		if fd == nil {
+			return "", "", fmt.Errorf("no field %q in %s", segment, current.Descriptor().FullName())
+		}
+		last := i == len(segments)-1
+
+		if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind {
+			if fd.IsMap() {
+				if !last {
+					return "", "", fmt.Errorf("cannot descend into map %s", fd.Name())
+				}
+				current.Mutable(fd)
+				return "map", "{}", nil
+			}
+			var next protoreflect.Message
+			if fd.IsList() {
+				list := current.Mutable(fd).List()
+				if list.Len() == 0 {
+					list.Append(list.NewElement())
+				}
+				next = list.Get(0).Message()
+			} else {
+				next = current.Mutable(fd).Message()
+			}
+			if last {
+				return string(fd.Message().FullName()), "{}", nil
+			}
+			current = next
+			continue
+		}
+
+		if !last {
+			return "", "", fmt.Errorf("%s is not a message", fd.Name())
+		}
+
+		value, text := scalarValue(fd, guess)
+		if fd.IsList() {
+			current.Mutable(fd).List().Append(value)
+		} else {
+			current.Set(fd, value)
+		}
+		return fd.Kind().String(), text, nil
+	}
+	return "", "", fmt.Errorf("empty field path")
+}
+
+// findField looks a field up by proto name, JSON name or case-insensitively
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd
+	}
+	normalized := strings.ReplaceAll(strings.ToLower(name), "_", "")
+	for i := 0; i < fields.Len(); i++ {
+		if strings.ReplaceAll(strings.ToLower(string(fields.Get(i).Name())), "_", "") == normalized {
+			return fields.Get(i)
+		}
+	}
+	return nil
+}
+
+// scalarValue builds a placeholder value of the field's kind
+func scalarValue(fd protoreflect.FieldDescriptor, guess SkeletonField) (protoreflect.Value, string) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(true), "true"
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		number := values.Get(0).Number()
+		if values.Len() > 1 {
+			number = values.Get(1).Number()
+		}
+		return protoreflect.ValueOfEnum(number), string(fd.Enum().Values().ByNumber(number).Name())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(1), "1"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(1), "1"
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(1), "1"
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(1), "1"
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(1), "1"
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(1), "1"
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte("test")), "test"
+	}
+
+	value := guess.Value
+	if guess.Type != "string" {
+		value = "test"
+	}
+	return protoreflect.ValueOfString(value), value
+}
+
+// printRequestSkeletons writes harvested request skeletons
+func (s *Scanner) printRequestSkeletons() {
+	if len(s.result.RequestSkeletons) == 0 {
+		return
+	}
+
+	fmt.Println("\nRequest Skeletons:")
+	for _, skeleton := range s.result.RequestSkeletons {
+		fmt.Printf("   %s (%s, %d rounds, final %s)\n", skeleton.Method, skeleton.Mode, skeleton.Rounds, skeleton.FinalCode)
+		for _, field := range skeleton.Fields {
+			if field.Number > 0 {
+				fmt.Printf("      %d: %s %s = %q\n", field.Number, field.Type, field.Name, field.Value)
+			} else {
+				fmt.Printf("      %s %s = %q\n", field.Type, field.Name, field.Value)
+			}
+		}
+		if len(skeleton.JSON) > 0 {
+			fmt.Printf("      JSON: %s\n", skeleton.JSON)
+		}
+	}
+}
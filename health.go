@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthNotFound is recorded when the health server does not know a service
+const healthNotFound = "NOT_FOUND"
+
+// HealthEvent records a status change observed on a Health.Watch stream
+type HealthEvent struct {
+	Service   string `json:"service"`
+	Previous  string `json:"previous"`
+	Status    string `json:"status"`
+	Timestamp string `json:"timestamp"`
+}
+
+// addCandidate remembers a service name that was tried during discovery
+func (s *Scanner) addCandidate(service string) {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	if s.candidates == nil {
+		s.candidates = make(map[string]bool)
+	}
+	s.candidates[service] = true
+}
+
+// healthCandidates returns every service name worth asking the health service about
+func (s *Scanner) healthCandidates() []string {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	seen := make(map[string]bool)
+	for service := range s.candidates {
+		seen[service] = true
+	}
+	for _, service := range s.result.AvailableServices {
+		seen[service] = true
+	}
+	// The health service itself is always registered with the empty name
+	delete(seen, "")
+
+	names := make([]string, 0, len(seen))
+	for service := range seen {
+		names = append(names, service)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkHealth asks Health.Check for the status of a single service name
+func (s *Scanner) checkHealth(ctx context.Context, client healthpb.HealthClient, service string) string {
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		if st, ok := status.FromError(err); ok && st.Code() == codes.NotFound {
+			return healthNotFound
+		}
+		return ""
+	}
+	return resp.GetStatus().String()
+}
+
+// enumerateHealth probes Health.Check for every candidate service, using the
+// health service as an existence oracle independent of method brute forcing
+func (s *Scanner) enumerateHealth(ctx context.Context) {
+	candidates := s.healthCandidates()
+	if len(candidates) == 0 {
+		return
+	}
+
+	fmt.Printf("\n[+] Probing health status for %d candidate services...\n", len(candidates))
+
+	client := healthpb.NewHealthClient(s.recording())
+	found := 0
+
+	var wg sync.WaitGroup
+	var foundMutex sync.Mutex
+	semaphore := make(chan struct{}, s.threads)
+
+	for _, candidate := range candidates {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(service string) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			state := s.checkHealth(ctx, client, service)
+			switch state {
+			case "":
+				return
+			case healthNotFound:
+				// Only worth recording for services we know exist by other means
+				if s.hasService(service) {
+					s.setHealthStatus(service, state)
+				}
+				return
+			}
+
+			s.setHealthStatus(service, state)
+			if !s.hasService(service) {
+				s.addService(service, "health")
+				foundMutex.Lock()
+				found++
+				foundMutex.Unlock()
+			}
+
+			if s.verbose {
+				fmt.Printf("   [+] %s: %s\n", service, state)
+			}
+		}(candidate)
+	}
+
+	wg.Wait()
+
+	fmt.Printf("[+] Health service reported %d services (%d not found by other methods)\n",
+		s.countHealthReported(), found)
+}
+
+// startHealthWatches watches the overall health and every service found so
+// far; services found later are watched by addService
+func (s *Scanner) startHealthWatches(ctx context.Context) {
+	s.resultMutex.Lock()
+	s.watchCtx = ctx
+	services := append([]string{""}, s.result.AvailableServices...)
+	s.resultMutex.Unlock()
+
+	for _, service := range services {
+		s.watchService(service)
+	}
+}
+
+// watchService opens a watch for a service once, while watches are active
+func (s *Scanner) watchService(service string) {
+	s.resultMutex.Lock()
+	ctx := s.watchCtx
+	if ctx == nil || s.watched[service] {
+		s.resultMutex.Unlock()
+		return
+	}
+	if s.watched == nil {
+		s.watched = make(map[string]bool)
+	}
+	s.watched[service] = true
+	s.resultMutex.Unlock()
+
+	s.watchHealth(ctx, service)
+}
+
+// holdHealthWatches keeps the watches open for the opt-in -health-watch
+// window so changes that follow the probing, such as a service recovering
+// after the scan load, are still recorded
+func (s *Scanner) holdHealthWatches() {
+	if s.healthWatch <= 0 {
+		return
+	}
+	s.resultMutex.Lock()
+	watched := len(s.watched)
+	s.resultMutex.Unlock()
+
+	fmt.Printf("[+] Watching health of %d services for %s...\n", watched, s.healthWatch)
+	time.Sleep(s.healthWatch)
+}
+
+// stopHealthWatches stops opening new watches and reports the changes seen
+func (s *Scanner) stopHealthWatches() {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	if s.watchCtx == nil {
+		return
+	}
+	s.watchCtx = nil
+	if len(s.result.HealthEvents) > 0 {
+		fmt.Printf("[!] Health status changed %d times during the scan\n", len(s.result.HealthEvents))
+	}
+}
+
+// watchHealth opens a Health.Watch stream and records status changes until
+// the watch context is cancelled
+func (s *Scanner) watchHealth(ctx context.Context, service string) {
+	client := healthpb.NewHealthClient(s.recording())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return
+	}
+
+	s.watchWG.Add(1)
+	go func() {
+		defer s.watchWG.Done()
+
+		previous := ""
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				return
+			}
+
+			current := resp.GetStatus().String()
+			// The first message is the current status, later ones are changes
+			if previous != "" && current != previous {
+				s.addHealthEvent(HealthEvent{
+					Service:   service,
+					Previous:  previous,
+					Status:    current,
+					Timestamp: time.Now().Format(time.RFC3339),
+				})
+				if s.verbose {
+					fmt.Printf("   [!] Health change for %q: %s -> %s\n", service, previous, current)
+				}
+			}
+			previous = current
+		}
+	}()
+}
+
+// Thread-safe health result updates
+func (s *Scanner) setHealthStatus(service, state string) {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	s.result.HealthStatus[service] = state
+}
+
+func (s *Scanner) addHealthEvent(event HealthEvent) {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	s.result.HealthEvents = append(s.result.HealthEvents, event)
+}
+
+func (s *Scanner) countHealthReported() int {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	count := 0
+	for service, state := range s.result.HealthStatus {
+		if service != "" && state != healthNotFound {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *Scanner) hasService(service string) bool {
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	for _, existing := range s.result.AvailableServices {
+		if existing == service {
+			return true
+		}
+	}
+	return false
+}
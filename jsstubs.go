@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Patterns left in frontend bundles by gRPC-Web and Connect code generators
+var (
+	// '/acme.user.v1.UserService/GetUser' from protoc-gen-grpc-web
+	// MethodDescriptors and rpcCall URLs
+	jsMethodPathPattern = regexp.MustCompile(`["'` + "`" + `]/((?:[A-Za-z_]\w*\.)*[A-Za-z_]\w*)/([A-Z]\w*)["'` + "`" + `]`)
+	// new ServiceType("acme.user.v1.UserService", [{ name: "GetUser", ... }]) from protobuf-ts
+	jsServiceTypePattern = regexp.MustCompile(`ServiceType\(\s*["']((?:[A-Za-z_]\w*\.)*[A-Za-z_]\w*)["']\s*,\s*\[`)
+	// { typeName: "acme.user.v1.UserService", methods: { getUser: { name: "GetUser", ... } } } from connect-es v1
+	jsTypeNamePattern = regexp.MustCompile(`typeName\s*:\s*["']((?:[A-Za-z_]\w*\.)*[A-Za-z_]\w*)["']\s*,\s*methods\s*:\s*\{`)
+	// name: "GetUser" inside a methods block
+	jsMethodNamePattern = regexp.MustCompile(`\bname\s*:\s*["']([A-Z]\w*)["']`)
+	// fileDesc("base64...") from protobuf-es v2, an embedded FileDescriptorProto
+	jsFileDescPattern = regexp.MustCompile(`fileDesc\(\s*["']([A-Za-z0-9+/=]{16,})["']`)
+)
+
+// extractJSStubs finds the services and methods generated client stubs
+// reference in JavaScript source
+func extractJSStubs(src string) []protoService {
+	var services []protoService
+
+	for _, match := range jsMethodPathPattern.FindAllStringSubmatch(src, -1) {
+		service := match[1]
+		// Skip ordinary URL paths like "/static/Logo" or "/cdn.example.com/Logo"
+		name := service[strings.LastIndex(service, ".")+1:]
+		if name[0] < 'A' || name[0] > 'Z' || (!strings.Contains(service, ".") && !strings.HasSuffix(service, "Service")) {
+			continue
+		}
+		services = append(services, splitServiceName(service, match[2]))
+	}
+
+	for _, pattern := range []*regexp.Regexp{jsServiceTypePattern, jsTypeNamePattern} {
+		for _, loc := range pattern.FindAllStringSubmatchIndex(src, -1) {
+			// The match ends on the bracket opening the method list
+			body := src[loc[1]-1 : jsBlockEnd(src, loc[1]-1)]
+			var methods []string
+			for _, m := range jsMethodNamePattern.FindAllStringSubmatch(body, -1) {
+				methods = append(methods, m[1])
+			}
+			services = append(services, splitServiceName(src[loc[2]:loc[3]], methods...))
+		}
+	}
+
+	for _, match := range jsFileDescPattern.FindAllStringSubmatch(src, -1) {
+		data, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(match[1], "="))
+		if err != nil {
+			continue
+		}
+		file := &descriptorpb.FileDescriptorProto{}
+		if proto.Unmarshal(data, file) != nil {
+			continue
+		}
+		services = append(services, descriptorServices(file)...)
+	}
+
+	return services
+}
+
+// extractSourceMapStubs runs the stub extractor over every source embedded
+// in a source map's sourcesContent
+func extractSourceMapStubs(data []byte) ([]protoService, error) {
+	var sourceMap struct {
+		Sources        []string `json:"sources"`
+		SourcesContent []string `json:"sourcesContent"`
+	}
+	if err := json.Unmarshal(data, &sourceMap); err != nil {
+		return nil, fmt.Errorf("failed to parse source map: %v", err)
+	}
+	if len(sourceMap.Sources) == 0 {
+		return nil, fmt.Errorf("not a source map: no sources")
+	}
+
+	var services []protoService
+	for _, content := range sourceMap.SourcesContent {
+		services = append(services, extractJSStubs(content)...)
+	}
+	return services, nil
+}
+
+// splitServiceName turns "acme.user.v1.UserService" into a protoService
+func splitServiceName(fullName string, methods ...string) protoService {
+	if pos := strings.LastIndex(fullName, "."); pos > 0 {
+		return protoService{Package: fullName[:pos], Name: fullName[pos+1:], Methods: methods}
+	}
+	return protoService{Name: fullName, Methods: methods}
+}
+
+// jsBlockEnd returns the index just past the bracket closing the one at
+// start, skipping string literals
+func jsBlockEnd(src string, start int) int {
+	open := src[start]
+	closer := map[byte]byte{'[': ']', '{': '}', '(': ')'}[open]
+	depth := 0
+	for i := start; i < len(src); i++ {
+		switch c := src[i]; c {
+		case '"', '\'', '`':
+			for i++; i < len(src) && src[i] != c; i++ {
+				if src[i] == '\\' {
+					i++
+				}
+			}
+		case open:
+			depth++
+		case closer:
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(src)
+}
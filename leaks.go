@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorMessage is a distinct status message or detail set returned while probing
+type ErrorMessage struct {
+	Method  string         `json:"method"`
+	Code    string         `json:"code"`
+	Message string         `json:"message,omitempty"`
+	Details []StatusDetail `json:"details,omitempty"`
+}
+
+// leakDetector flags a class of sensitive information in error text
+type leakDetector struct {
+	ID          string
+	Title       string
+	Severity    string
+	Pattern     *regexp.Regexp
+	Remediation string
+}
+
+var leakDetectors = []leakDetector{
+	{
+		ID:       "verbose-error-stack-trace",
+		Title:    "Error message leaks a stack trace",
+		Severity: "medium",
+		// Fragments of stack traces from common server runtimes
+		Pattern:     regexp.MustCompile(`goroutine \d+ \[|panic: |\.go:\d+|\bat [\w.$]+\([\w]+\.java:\d+\)|Traceback \(most recent call last\)|File "[^"]+", line \d+|\.cs:line \d+|\.rb:\d+:in `),
+		Remediation: "Return generic status messages and log stack traces server-side; never attach DebugInfo to external responses.",
+	},
+	{
+		ID:          "verbose-error-sql",
+		Title:       "Error message leaks SQL",
+		Severity:    "medium",
+		Pattern:     regexp.MustCompile(`(?i)\bselect\s+[\w*,\s.]+\s+from\s+\w+|\binsert\s+into\s+\w+|\bupdate\s+\w+\s+set\b|\bdelete\s+from\s+\w+|SQLSTATE|syntax error at or near|\bORA-\d{5}\b|You have an error in your SQL syntax|\bpq: |sqlite3?: |duplicate key value violates`),
+		Remediation: "Map database errors to generic status messages before returning them.",
+	},
+	{
+		ID:          "verbose-error-file-path",
+		Title:       "Error message leaks server file paths",
+		Severity:    "low",
+		Pattern:     regexp.MustCompile(`(?:^|[\s"'(=:])(/(?:home|usr|var|opt|srv|app|src|go|root|etc|tmp|build|workspace)/[\w.@+-]+(?:/[\w.@+-]+)+|[A-Za-z]:\\(?:[\w .-]+\\)+[\w .-]+)`),
+		Remediation: "Strip filesystem paths from errors returned to clients.",
+	},
+	{
+		ID:          "verbose-error-internal-host",
+		Title:       "Error message leaks internal hostnames or IPs",
+		Severity:    "low",
+		Pattern:     regexp.MustCompile(`\b(?:10\.\d{1,3}\.\d{1,3}\.\d{1,3}|172\.(?:1[6-9]|2\d|3[01])\.\d{1,3}\.\d{1,3}|192\.168\.\d{1,3}\.\d{1,3}|127\.\d{1,3}\.\d{1,3}\.\d{1,3})\b|\b[\w-]+(?:\.[\w-]+)*\.(?:internal|local|lan|corp|intranet|cluster\.local)\b`),
+		Remediation: "Do not include upstream addresses or internal DNS names in client-facing errors.",
+	},
+	{
+		ID:          "verbose-error-version",
+		Title:       "Error message leaks software versions",
+		Severity:    "info",
+		Pattern:     regexp.MustCompile(`(?i)\b(?:grpc-go|grpc-java|grpc-node|grpc-dotnet|grpc-python|netty|envoy|nginx|openssl|spring(?:-boot)?|express|node|python|java|go|postgres(?:ql)?|mysql|redis)[/ -]v?\d+\.\d+(?:\.\d+)?`),
+		Remediation: "Remove version banners from error messages to slow down targeted exploitation.",
+	},
+}
+
+// recordErrorMessage keeps each distinct status message and its details
+func (s *Scanner) recordErrorMessage(path string, err error) {
+	st, ok := status.FromError(err)
+	if !ok || err == nil {
+		return
+	}
+
+	switch st.Code() {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		// Client-side transport errors, not server output
+		return
+	case codes.Unimplemented:
+		// Routing boilerplate carries the probed name and would flood the list
+		msg := strings.ToLower(st.Message())
+		if strings.Contains(msg, "unknown service") || strings.Contains(msg, "unknown method") {
+			return
+		}
+	}
+
+	details := decodeStatusDetails(st)
+	if st.Message() == "" && len(details) == 0 {
+		return
+	}
+
+	key := st.Code().String() + "\x00" + st.Message() + "\x00" + strings.Join(detailText(details), "\x00")
+
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	if s.errorsSeen == nil {
+		s.errorsSeen = make(map[string]bool)
+	}
+	if s.errorsSeen[key] {
+		return
+	}
+	s.errorsSeen[key] = true
+
+	s.result.ErrorMessages = append(s.result.ErrorMessages, ErrorMessage{
+		Method:  strings.TrimPrefix(path, "/"),
+		Code:    st.Code().String(),
+		Message: st.Message(),
+		Details: details,
+	})
+}
+
+// analyzeErrorLeaks runs the leak detectors over every collected error message
+func (s *Scanner) analyzeErrorLeaks() {
+	messages := append([]ErrorMessage{}, s.result.ErrorMessages...)
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[i].Method < messages[j].Method
+	})
+
+	reported := make(map[string]bool)
+	for _, msg := range messages {
+		text := strings.Join(append([]string{msg.Message}, detailText(msg.Details)...), "\n")
+		for _, detector := range leakDetectors {
+			match := detector.Pattern.FindString(text)
+			if match == "" {
+				continue
+			}
+
+			key := detector.ID + "\x00" + msg.Method + "\x00" + strings.TrimSpace(match)
+			if reported[key] {
+				continue
+			}
+			reported[key] = true
+
+			s.addFinding(Finding{
+				ID:          detector.ID,
+				Title:       detector.Title,
+				Severity:    detector.Severity,
+				Method:      msg.Method,
+				Evidence:    fmt.Sprintf("%s matched %q in: %s", msg.Code, strings.TrimSpace(match), truncate(strings.ReplaceAll(text, "\n", " | "), 160)),
+				Remediation: detector.Remediation,
+			})
+		}
+	}
+}
+
+// printErrorMessages lists the distinct error messages in verbose output
+func (s *Scanner) printErrorMessages() {
+	if !s.verbose || len(s.result.ErrorMessages) == 0 {
+		return
+	}
+
+	fmt.Printf("\nError Messages (%d distinct):\n", len(s.result.ErrorMessages))
+	for _, msg := range s.result.ErrorMessages {
+		fmt.Printf("   %s  %s: %s\n", msg.Method, msg.Code, truncate(msg.Message, 120))
+		printStatusDetails(msg.Details, "      ")
+	}
+}
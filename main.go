@@ -1,11 +1,11 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"strings"
@@ -15,20 +15,38 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
-	"google.golang.org/grpc/credentials/insecure"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 // ScanResult holds the results of a gRPC service scan
 type ScanResult struct {
-	Target            string              `json:"target"`
-	AvailableServices []string            `json:"available_services"`
-	MethodsFound      map[string][]string `json:"methods_found,omitempty"`
-	ReflectionEnabled bool                `json:"reflection_enabled"`
-	ScanMode          string              `json:"scan_mode"` // "reflection", "bruteforce", or "standard"
-	Timestamp         string              `json:"timestamp"`
+	Target            string                 `json:"target"`
+	AvailableServices []string               `json:"available_services"`
+	MethodsFound      map[string][]string    `json:"methods_found,omitempty"`
+	ReflectionEnabled bool                   `json:"reflection_enabled"`
+	ScanMode          string                 `json:"scan_mode"` // "reflection", "bruteforce", or "standard"
+	Transport         string                 `json:"transport,omitempty"`
+	Endpoint          *EndpointInfo          `json:"endpoint,omitempty"`
+	HealthStatus      map[string]string      `json:"health_status,omitempty"`
+	HealthEvents      []HealthEvent          `json:"health_events,omitempty"`
+	MethodStatus      map[string]ProbeStatus `json:"method_status,omitempty"`
+	AdminServices     []AdminService         `json:"admin_services,omitempty"`
+	Channelz          *ChannelzInfo          `json:"channelz,omitempty"`
+	AuthMatrix        *AuthMatrix            `json:"auth_matrix,omitempty"`
+	BypassAttempts    []BypassAttempt        `json:"bypass_attempts,omitempty"`
+	JWTResults        []JWTTestResult        `json:"jwt_results,omitempty"`
+	Spray             *SprayResult           `json:"spray,omitempty"`
+	ErrorMessages     []ErrorMessage         `json:"error_messages,omitempty"`
+	RequestSkeletons  []RequestSkeleton      `json:"request_skeletons,omitempty"`
+	Gateway           *GatewayResult         `json:"gateway,omitempty"`
+	ResponseMetadata  *ResponseMetadata      `json:"response_metadata,omitempty"`
+	Findings          []Finding              `json:"findings,omitempty"`
+	Timestamp         string                 `json:"timestamp"`
 }
 
 // Scanner encapsulates the scanning logic
@@ -39,9 +57,39 @@ type Scanner struct {
 	wordlist    string
 	methodsList string
 	threads     int
-	conn        *grpc.ClientConn
+	top         int
+	minSeverity string
+	allFindings []Finding // every finding, before the -min-severity filter
+	credentials []Credential
+	authMatrix  bool
+	bypass      bool
+	jwtTests    bool
+	jwtPubKey   string
+	sprayMax    int
+	sprayDelay  time.Duration
+	sprayStop   bool
+	harvest     bool
+	gateway     bool
+	gatewayURL  string
+	transport   string
+	useTLS      bool
+	conn        Conn
 	result      *ScanResult
 	resultMutex sync.Mutex
+
+	// Health service enumeration state
+	healthAvailable bool
+	healthWatch     time.Duration
+	candidates      map[string]bool
+	watchCtx        context.Context // set while Health.Watch streams are open
+	watched         map[string]bool
+	watchWG         sync.WaitGroup
+
+	// Distinct status messages seen while probing
+	errorsSeen map[string]bool
+
+	// Descriptors resolved through server reflection
+	descriptors *protoregistry.Files
 }
 
 // Common service patterns - simplified but comprehensive
@@ -85,19 +133,48 @@ func main() {
 	var (
 		target      = flag.String("target", "localhost:50051", "gRPC server address")
 		timeout     = flag.Int("timeout", 10, "Timeout in seconds")
+		healthWatch = flag.Int("health-watch", 0, "Extra seconds to keep Health.Watch streams open after the scan (default: changes during the scan only)")
 		output      = flag.String("output", "", "Output file for results (default: stdout)")
 		verbose     = flag.Bool("v", false, "Verbose output")
 		simple      = flag.Bool("simple", false, "Simple output (service names only)")
-		wordlist    = flag.String("wordlist", "", "Path to wordlist file for service brute forcing")
+		wordlist    = flag.String("wordlist", "", "Wordlist file for service brute forcing, or builtin:NAME (see 'wordlist list')")
 		methodsList = flag.String("methods", "", "Path to methods wordlist (optional)")
 		threads     = flag.Int("threads", 10, "Number of concurrent threads for brute forcing")
+		top         = flag.Int("top", 0, "Only try the first N wordlist entries, highest scored first (0 = all)")
 		call        = flag.String("call", "", "Call a specific method on a service (format: Service/Method or Service.Method)")
+		replay      = flag.String("replay", "", "Captured calls file from 'wordlist -input=<har|pcap>' whose request -call replays")
 		service     = flag.String("service", "", "Test whether a specific service exists (can specify multiple with commas)")
 		method      = flag.String("method", "", "Test specific methods (can specify multiple with commas)")
+		minSeverity = flag.String("min-severity", "info", "Only report findings at or above this severity (info, low, medium, high, critical)")
+		failOn      = flag.String("fail-on", "", "Exit with status 2 if any finding is at or above this severity (for CI gating)")
+		authMatrix  = flag.Bool("auth-matrix", false, "Call every confirmed method with no credentials and with each credential set")
+		bypass      = flag.Bool("bypass", false, "Replay protected methods with path variants to probe for authorization bypasses")
+		jwtTests    = flag.Bool("jwt-tests", false, "Replay confirmed methods with tampered variants of the -bearer JWT")
+		jwtPubKey   = flag.String("jwt-pubkey", "", "PEM public key for the HS256 key confusion JWT variant (optional)")
+		transport   = flag.String("transport", transportAuto, "Transport: auto, native, grpc-web, grpc-web-text, connect or connect-json")
+		useTLS      = flag.Bool("tls", false, "Connect with TLS (certificate verification disabled)")
+		harvest     = flag.Bool("harvest", false, "Build request skeletons from BadRequest field violations")
+		gateway     = flag.Bool("gateway", false, "Probe grpc-gateway/JSON transcoding routes and import served OpenAPI documents")
+		gatewayURL  = flag.String("gateway-url", "", "Base URL of the REST gateway (default: the target over http or https)")
+		spray       = flag.String("spray", "", "Spray candidate credentials against one protected method (format: Service/Method)")
+		sprayList   = flag.String("spray-list", "", "File of candidate API keys or tokens for -spray, one per line")
+		sprayKeys   = flag.String("spray-keys", strings.Join(defaultSprayKeys, ","), "Metadata keys to send each -spray credential in")
+		sprayMax    = flag.Int("spray-max", 20, "Maximum number of -spray attempts")
+		sprayDelay  = flag.Int("spray-delay", 500, "Delay between -spray attempts in milliseconds")
+		sprayStop   = flag.Bool("spray-stop", true, "Stop -spray on the first accepted credential")
+		bearers     stringList
+		apiKeys     stringList
+		basics      stringList
+		headers     stringList
 		help        = flag.Bool("help", false, "Show help message")
 		h           = flag.Bool("h", false, "Show help message")
 	)
 
+	flag.Var(&bearers, "bearer", "Bearer token credential (repeatable)")
+	flag.Var(&apiKeys, "api-key", "API key credential sent as x-api-key (repeatable)")
+	flag.Var(&basics, "basic", "Basic auth credential as user:password (repeatable)")
+	flag.Var(&headers, "header", "Custom header credential as \"name: value\" (repeatable)")
+
 	flag.Parse()
 
 	// Show help if requested or no target provided
@@ -115,20 +192,46 @@ func main() {
 		fmt.Println("\nWordlist Generation:")
 		fmt.Println("  grpc-scanner wordlist -url=https://api.example.com/docs -output=wordlist.txt")
 		fmt.Println("  grpc-scanner wordlist -input=api_docs.html -output=wordlist.txt")
+		fmt.Println("  grpc-scanner wordlist -input=session.har -output=wordlist.txt -calls=captured_calls.json")
+		fmt.Println("  grpc-scanner wordlist list                List the built-in wordlists")
 		fmt.Println("\nExamples:")
 		fmt.Println("  grpc-scanner -target=api.example.com:443")
-		fmt.Println("  grpc-scanner -target=api.example.com:443 -wordlist=data/grpc_wordlist.txt")
+		fmt.Println("  grpc-scanner -target=api.example.com:443 -wordlist=builtin:default")
 		fmt.Println("  grpc-scanner detect -targets=domains.txt -threads=200 -output=grpc_targets.txt")
+		fmt.Println("  grpc-scanner -target=api.example.com:443 -tls -gateway -gateway-url=https://api.example.com")
 		fmt.Println("\nDirect Testing (no protobuf files needed!):")
 		fmt.Println("  grpc-scanner -target=api.example.com:443 -call=UserService/GetUser")
+		fmt.Println("  grpc-scanner -target=api.example.com:443 -call=UserService/GetUser -replay=captured_calls.json")
 		fmt.Println("  grpc-scanner -target=api.example.com:443 -service=UserService -method=GetUser,ListUsers")
 		fmt.Println("  grpc-scanner -target=api.example.com:443 -service=UserService,AuthService")
+		fmt.Println("\nCredential Spraying (authorized testing only):")
+		fmt.Println("  grpc-scanner -target=api.example.com:443 -spray=SecureService/ListSecrets -spray-list=keys.txt -spray-max=50")
 		return
 	}
 
+	if severityRank(*minSeverity) < 0 {
+		log.Fatalf("Invalid -min-severity %q (use one of: %s)", *minSeverity, strings.Join(severityLevels, ", "))
+	}
+	if *failOn != "" && severityRank(*failOn) < 0 {
+		log.Fatalf("Invalid -fail-on %q (use one of: %s)", *failOn, strings.Join(severityLevels, ", "))
+	}
+
+	if !validTransport(*transport) {
+		log.Fatalf("Invalid -transport %q (use one of: %s)", *transport, strings.Join(transportNames, ", "))
+	}
+
+	credentials, err := buildCredentials(bearers, apiKeys, basics, headers)
+	if err != nil {
+		log.Fatalf("Invalid credential: %v", err)
+	}
+
+	if *replay != "" && *call == "" {
+		log.Fatalf("-replay requires -call")
+	}
+
 	// Handle direct call mode
 	if *call != "" {
-		handleDirectCall(*target, *call, time.Duration(*timeout)*time.Second, *verbose)
+		handleDirectCall(*target, *call, time.Duration(*timeout)*time.Second, *verbose, *output, *transport, *useTLS, *replay)
 		return
 	}
 
@@ -136,22 +239,65 @@ func main() {
 	scanner := &Scanner{
 		target:      *target,
 		timeout:     time.Duration(*timeout) * time.Second,
+		healthWatch: time.Duration(*healthWatch) * time.Second,
 		verbose:     *verbose,
 		wordlist:    *wordlist,
 		methodsList: *methodsList,
 		threads:     *threads,
+		top:         *top,
+		minSeverity: *minSeverity,
+		credentials: credentials,
+		authMatrix:  *authMatrix,
+		bypass:      *bypass,
+		jwtTests:    *jwtTests,
+		jwtPubKey:   *jwtPubKey,
+		sprayMax:    *sprayMax,
+		sprayDelay:  time.Duration(*sprayDelay) * time.Millisecond,
+		sprayStop:   *sprayStop,
+		harvest:     *harvest,
+		gateway:     *gateway,
+		gatewayURL:  *gatewayURL,
+		transport:   *transport,
+		useTLS:      *useTLS,
 		result: &ScanResult{
 			Target:            *target,
 			AvailableServices: []string{},
 			MethodsFound:      make(map[string][]string),
+			HealthStatus:      make(map[string]string),
+			MethodStatus:      make(map[string]ProbeStatus),
 			Timestamp:         time.Now().Format(time.RFC3339),
 		},
 	}
 
+	// Handle credential spraying
+	if *spray != "" {
+		if *sprayList == "" {
+			log.Fatalf("-spray requires -spray-list")
+		}
+		keys := []string{}
+		for _, key := range strings.Split(*sprayKeys, ",") {
+			if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		scanner.result.ScanMode = "spray"
+		scanner.handleSpray(*spray, *sprayList, keys)
+		scanner.analyzeFindings()
+		if *output != "" {
+			scanner.SaveResults(*output)
+		} else {
+			scanner.PrintResults()
+		}
+		exitOnFindings(scanner, *failOn)
+		return
+	}
+
 	// Handle direct service/method testing
 	if *service != "" || *method != "" {
 		scanner.handleDirectTesting(*service, *method)
+		scanner.analyzeFindings()
 		scanner.PrintResults()
+		exitOnFindings(scanner, *failOn)
 		return
 	}
 
@@ -159,6 +305,7 @@ func main() {
 	if err := scanner.Run(); err != nil {
 		log.Fatalf("Scan failed: %v", err)
 	}
+	scanner.analyzeFindings()
 
 	// Output results
 	if *output != "" {
@@ -168,6 +315,16 @@ func main() {
 	} else {
 		scanner.PrintResults()
 	}
+
+	exitOnFindings(scanner, *failOn)
+}
+
+// exitOnFindings exits with status 2 when a finding meets the -fail-on severity
+func exitOnFindings(s *Scanner, failOn string) {
+	if failOn != "" && s.hasFindingAtLeast(failOn) {
+		fmt.Fprintf(os.Stderr, "[!] Findings at or above %q severity, exiting with status 2\n", failOn)
+		os.Exit(2)
+	}
 }
 
 // Run executes the scan
@@ -178,12 +335,29 @@ func (s *Scanner) Run() error {
 
 	fmt.Printf("[+] Scanning %s...\n", s.target)
 
-	conn, err := grpc.DialContext(ctx, s.target, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return fmt.Errorf("failed to connect: %v", err)
+	// Classify the endpoint before committing to a transport
+	endpoint := classifyEndpoint(ctx, s.target, s.transport, s.useTLS)
+	s.result.Endpoint = endpoint
+	if !endpoint.IsGRPC() {
+		// An explicitly chosen HTTP transport may still work on servers
+		// without a health service, which the pre-flight relies on
+		if s.transport == transportAuto || s.transport == transportNative {
+			fmt.Printf("[!] %s does not appear to be a gRPC service\n", s.target)
+			fmt.Printf("   Detected: %s\n", endpoint.Describe())
+			return fmt.Errorf("not a gRPC service")
+		}
+		fmt.Printf("[!] Pre-flight got no gRPC response (%s), continuing with %s\n", endpoint.Describe(), s.transport)
+	} else {
+		fmt.Printf("[+] Endpoint: %s\n", endpoint.Describe())
+		if s.transport == transportAuto {
+			s.transport = endpoint.Transport
+		}
 	}
-	defer conn.Close()
-	s.conn = conn
+
+	if err := s.connect(ctx); err != nil {
+		return err
+	}
+	defer s.conn.Close()
 
 	// Wait for connection
 	if !s.waitForConnection(ctx) {
@@ -192,17 +366,9 @@ func (s *Scanner) Run() error {
 		return fmt.Errorf("connection failed")
 	}
 
-	// Test if this is actually a gRPC service
-	isGRPC, serviceType := s.detectServiceType(ctx)
-	if !isGRPC {
-		fmt.Printf("[!] %s does not appear to be a gRPC service\n", s.target)
-		fmt.Printf("   Detected: %s\n", serviceType)
-		return fmt.Errorf("not a gRPC service")
-	}
-
 	fmt.Printf("[+] Connected to gRPC service at %s\n", s.target)
-	if s.verbose {
-		fmt.Printf("   Connection state: %s\n", s.conn.GetState())
+	if native, ok := s.conn.(*grpc.ClientConn); ok && s.verbose {
+		fmt.Printf("   Connection state: %s\n", native.GetState())
 	}
 
 	// Try reflection first
@@ -217,6 +383,17 @@ func (s *Scanner) Run() error {
 	fmt.Println("\n[+] Checking standard gRPC services...")
 	s.checkStandardServices(ctx)
 
+	// Look for exposed admin and debug services
+	s.checkAdminServices(ctx)
+
+	// Watch the overall health and every service from its discovery on. The
+	// watches outlive the scan deadline until the -health-watch window ends.
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if s.healthAvailable {
+		s.startHealthWatches(watchCtx)
+	}
+
 	// If no services found or reflection not available, use brute force
 	if !s.result.ReflectionEnabled || len(s.result.AvailableServices) <= 1 {
 		if s.wordlist != "" {
@@ -234,23 +411,55 @@ func (s *Scanner) Run() error {
 		s.result.ScanMode = "standard"
 	}
 
+	// Use the health service as an independent existence oracle
+	if s.healthAvailable {
+		s.enumerateHealth(ctx)
+		s.holdHealthWatches()
+	}
+
+	s.stopHealthWatches()
+	stopWatch()
+	s.watchWG.Wait()
+
+	if s.authMatrix {
+		s.runAuthMatrix()
+	}
+	if s.bypass {
+		s.runBypassProbes()
+	}
+	if s.jwtTests {
+		s.runJWTTests()
+	}
+	if s.harvest {
+		s.runHarvest()
+	}
+	if s.gateway {
+		s.runGateway()
+	}
+
 	return nil
 }
 
 // waitForConnection waits for the gRPC connection to be ready
 func (s *Scanner) waitForConnection(ctx context.Context) bool {
+	// HTTP-based transports have no persistent connection to wait for
+	native, ok := s.conn.(*grpc.ClientConn)
+	if !ok {
+		return true
+	}
+
 	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	for {
-		state := s.conn.GetState()
+		state := native.GetState()
 		if state == connectivity.Ready || state == connectivity.Idle {
 			return true
 		}
 		if state == connectivity.TransientFailure || state == connectivity.Shutdown {
 			return false
 		}
-		if !s.conn.WaitForStateChange(waitCtx, state) {
+		if !native.WaitForStateChange(waitCtx, state) {
 			if s.verbose {
 				log.Printf("Connection state: %s", state)
 			}
@@ -259,49 +468,9 @@ func (s *Scanner) waitForConnection(ctx context.Context) bool {
 	}
 }
 
-// detectServiceType attempts to determine if the endpoint is a gRPC service
-func (s *Scanner) detectServiceType(ctx context.Context) (bool, string) {
-	// Try a simple gRPC call to test if it's a gRPC service
-	err := s.conn.Invoke(ctx, "/grpc.health.v1.Health/Check", nil, nil)
-	if err == nil {
-		return true, "gRPC service with health check"
-	}
-
-	// Check the error to determine service type
-	st, ok := status.FromError(err)
-	if !ok {
-		// Not a gRPC error - might be HTTP or other protocol
-		errStr := err.Error()
-		if strings.Contains(errStr, "HTTP") {
-			return false, "HTTP/REST service"
-		}
-		if strings.Contains(errStr, "connection refused") {
-			return false, "No service listening on this port"
-		}
-		return false, "Unknown service type"
-	}
-
-	// It's a gRPC error - this confirms it's a gRPC service
-	switch st.Code() {
-	case codes.Unimplemented:
-		if strings.Contains(st.Message(), "unknown service") {
-			return true, "gRPC service (health check not implemented)"
-		}
-		return true, "gRPC service"
-	case codes.Unavailable:
-		return false, "Service unavailable"
-	case codes.Internal:
-		// Some gRPC services return Internal for unimplemented services
-		return true, "gRPC service"
-	default:
-		// Any other gRPC error code means it's a gRPC service
-		return true, "gRPC service"
-	}
-}
-
 // tryReflection attempts to use server reflection for service discovery
 func (s *Scanner) tryReflection(ctx context.Context) bool {
-	client := grpc_reflection_v1alpha.NewServerReflectionClient(s.conn)
+	client := grpc_reflection_v1alpha.NewServerReflectionClient(s.recording())
 	stream, err := client.ServerReflectionInfo(ctx)
 	if err != nil {
 		return false
@@ -341,8 +510,10 @@ func (s *Scanner) tryReflection(ctx context.Context) bool {
 // checkStandardServices checks for common gRPC services
 func (s *Scanner) checkStandardServices(ctx context.Context) {
 	// Check health service
-	healthClient := healthpb.NewHealthClient(s.conn)
-	if _, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+	healthClient := healthpb.NewHealthClient(s.recording())
+	if resp, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{}); err == nil {
+		s.healthAvailable = true
+		s.result.HealthStatus[""] = resp.GetStatus().String()
 		s.addService("grpc.health.v1.Health", "standard")
 		s.addMethod("grpc.health.v1.Health", "Check")
 	}
@@ -364,71 +535,41 @@ func (s *Scanner) checkStandardServices(ctx context.Context) {
 type WordlistEntry struct {
 	Service string
 	Methods []string
+	Score   int // from a "# score=N" annotation, 0 if absent
+	// Defaults is set when the service also appeared on a bare line, so the
+	// default methods are tried along with Methods
+	Defaults bool
 }
 
-// loadEnhancedWordlist reads services and methods from an enhanced wordlist file
-func (s *Scanner) loadEnhancedWordlist(path string) ([]WordlistEntry, []string, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to open wordlist: %v", err)
+// loadEnhancedWordlist reads services, global methods and package prefixes
+// from an enhanced wordlist file. Exact duplicate services are merged and
+// names that are not valid gRPC identifiers skipped.
+func (s *Scanner) loadEnhancedWordlist(path string) ([]WordlistEntry, []string, []string, error) {
+	set := newWordlistSet()
+	if err := set.readFile(path); err != nil {
+		return nil, nil, nil, err
 	}
-	defer file.Close()
-
-	var entries []WordlistEntry
-	var globalMethods []string
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
-			continue
-		}
 
-		// Check for method-only entries (start with *)
-		if strings.HasPrefix(line, "*") {
-			method := strings.TrimPrefix(line, "*")
-			globalMethods = append(globalMethods, method)
-			continue
-		}
-
-		// Check for service:methods format
-		if strings.Contains(line, ":") {
-			parts := strings.SplitN(line, ":", 2)
-			service := strings.TrimSpace(parts[0])
-			methodList := strings.Split(parts[1], ",")
-
-			var methods []string
-			for _, m := range methodList {
-				if method := strings.TrimSpace(m); method != "" {
-					methods = append(methods, method)
-				}
+	if s.verbose && set.stats.Duplicates > 0 {
+		fmt.Printf("[+] Merged %d duplicate service entries in wordlist\n", set.stats.Duplicates)
+	}
+	if len(set.stats.Invalid) > 0 {
+		fmt.Printf("[!] Skipped %d invalid names in wordlist\n", len(set.stats.Invalid))
+		if s.verbose {
+			for _, msg := range set.stats.Invalid {
+				fmt.Printf("   %s\n", msg)
 			}
-
-			entries = append(entries, WordlistEntry{
-				Service: service,
-				Methods: methods,
-			})
-		} else {
-			// Simple service name
-			entries = append(entries, WordlistEntry{
-				Service: line,
-				Methods: nil, // Will use default methods
-			})
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, nil, fmt.Errorf("error reading wordlist: %v", err)
-	}
-
-	return entries, globalMethods, nil
+	// Annotated wordlists are tried highest score first
+	return set.sorted(), set.globalMethods, set.packages, nil
 }
 
 // wordlistBruteForce performs service discovery using a wordlist
 func (s *Scanner) wordlistBruteForce(ctx context.Context) error {
 	// Load enhanced wordlist
-	entries, globalMethods, err := s.loadEnhancedWordlist(s.wordlist)
+	entries, globalMethods, packages, err := s.loadEnhancedWordlist(s.wordlist)
 	if err != nil {
 		return err
 	}
@@ -454,9 +595,16 @@ func (s *Scanner) wordlistBruteForce(ctx context.Context) error {
 	}
 
 	fmt.Printf("[+] Loaded %d service entries from wordlist\n", len(entries))
+	if s.top > 0 && len(entries) > s.top {
+		entries = entries[:s.top]
+		fmt.Printf("[+] Trying only the top %d entries\n", s.top)
+	}
 	if len(globalMethods) > 0 {
 		fmt.Printf("[+] Loaded %d global methods\n", len(globalMethods))
 	}
+	if len(packages) > 0 {
+		fmt.Printf("[+] Loaded %d package prefixes\n", len(packages))
+	}
 	fmt.Printf("[+] Using %d threads for parallel scanning\n", s.threads)
 
 	// Progress tracking
@@ -480,6 +628,13 @@ func (s *Scanner) wordlistBruteForce(ctx context.Context) error {
 			methodsToTry := e.Methods
 			if len(methodsToTry) == 0 {
 				methodsToTry = defaultMethods
+			} else if e.Defaults {
+				methodsToTry = append([]string(nil), e.Methods...)
+				for _, m := range defaultMethods {
+					if !stringInSlice(methodsToTry, m) {
+						methodsToTry = append(methodsToTry, m)
+					}
+				}
 			}
 
 			// Try the service name with various patterns
@@ -497,7 +652,19 @@ func (s *Scanner) wordlistBruteForce(ctx context.Context) error {
 				)
 			}
 
+			// Try short names under every package prefix from the wordlist
+			if !strings.Contains(e.Service, ".") {
+				for _, pkg := range packages {
+					patterns = append(patterns, pkg+"."+e.Service)
+					if !strings.HasSuffix(e.Service, "Service") {
+						patterns = append(patterns, pkg+"."+e.Service+"Service")
+					}
+				}
+			}
+
 			for _, pattern := range patterns {
+				s.addCandidate(pattern)
+
 				// Try with first method to check if service exists
 				if len(methodsToTry) > 0 && s.checkService(ctx, pattern, methodsToTry[0]) {
 					s.addService(pattern, "wordlist")
@@ -554,6 +721,8 @@ func (s *Scanner) smartBruteForce(ctx context.Context) {
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
+			s.addCandidate(p.Service)
+
 			// Quick check with first method
 			if s.checkService(ctx, p.Service, p.Methods[0]) {
 				s.addService(p.Service, "bruteforce")
@@ -656,10 +825,100 @@ func (s *Scanner) generateMethodsForService(serviceName string, baseMethods []st
 	return methods
 }
 
+// invoke calls a method with an empty request, discarding the reply
+func (s *Scanner) invoke(ctx context.Context, service, method string) error {
+	return s.invokePath(ctx, fmt.Sprintf("/%s/%s", service, method))
+}
+
+// invokePath calls a raw method path and records the status message, headers
+// and trailers it returns
+func (s *Scanner) invokePath(ctx context.Context, path string, opts ...grpc.CallOption) error {
+	return s.invokeOn(ctx, s.conn, path, opts...)
+}
+
+// invokeOn is invokePath over a given connection
+func (s *Scanner) invokeOn(ctx context.Context, conn grpc.ClientConnInterface, path string, opts ...grpc.CallOption) error {
+	var header, trailer metadata.MD
+	opts = append(opts, grpc.Header(&header), grpc.Trailer(&trailer))
+	err := invokeEmpty(ctx, conn, path, opts...)
+	s.recordCall(path, header, trailer, err)
+	return err
+}
+
+// invokeEmpty sends an empty request and reads at most one response message.
+// The call is opened as a server stream so a streaming handler answers with its
+// first message instead of blocking until the deadline; a response message or
+// a clean end of stream means the handler answered.
+func invokeEmpty(ctx context.Context, conn grpc.ClientConnInterface, fullMethod string, opts ...grpc.CallOption) error {
+	// Connect serves unary procedures only as unary POSTs, so try those first
+	// and use a stream for the streaming procedures that refuse them
+	if connect, ok := conn.(*connectConn); ok {
+		if refused, err := connect.unary(ctx, fullMethod, &emptypb.Empty{}, &emptypb.Empty{}, opts...); !refused {
+			return err
+		}
+	}
+	return invokeStream(ctx, conn, fullMethod, []interface{}{&emptypb.Empty{}}, opts...)
+}
+
+// invokeStream sends the request messages on a stream and reads at most one
+// response message, which works for every method type: the stream only
+// streams on the client side when there are several requests.
+func invokeStream(ctx context.Context, conn grpc.ClientConnInterface, fullMethod string, requests []interface{}, opts ...grpc.CallOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Fill the header and trailer here rather than through the call options,
+	// which grpc-go only sets when the stream finishes, after cancel below
+	var header, trailer *metadata.MD
+	streamOpts := make([]grpc.CallOption, 0, len(opts))
+	for _, opt := range opts {
+		switch opt := opt.(type) {
+		case grpc.HeaderCallOption:
+			header = opt.HeaderAddr
+		case grpc.TrailerCallOption:
+			trailer = opt.TrailerAddr
+		default:
+			streamOpts = append(streamOpts, opt)
+		}
+	}
+
+	desc := &grpc.StreamDesc{ServerStreams: true, ClientStreams: len(requests) > 1}
+	stream, err := conn.NewStream(ctx, desc, fullMethod, streamOpts...)
+	if err != nil {
+		return err
+	}
+	// io.EOF from SendMsg means the server already ended the call; the status
+	// comes from RecvMsg
+	for _, request := range requests {
+		if err := stream.SendMsg(request); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+
+	err = stream.RecvMsg(&emptypb.Empty{})
+	if header != nil {
+		*header, _ = stream.Header()
+	}
+	if err == nil {
+		return nil
+	}
+	if trailer != nil {
+		*trailer = stream.Trailer()
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
 // checkService checks if a service exists by trying a method
 func (s *Scanner) checkService(ctx context.Context, service, method string) bool {
-	fullMethod := fmt.Sprintf("/%s/%s", service, method)
-	err := s.conn.Invoke(ctx, fullMethod, nil, nil)
+	err := s.invoke(ctx, service, method)
 
 	if err == nil {
 		return true
@@ -694,10 +953,10 @@ func (s *Scanner) checkService(ctx context.Context, service, method string) bool
 
 // checkMethod checks if a specific method exists
 func (s *Scanner) checkMethod(ctx context.Context, service, method string) bool {
-	fullMethod := fmt.Sprintf("/%s/%s", service, method)
-	err := s.conn.Invoke(ctx, fullMethod, nil, nil)
+	err := s.invoke(ctx, service, method)
 
 	if err == nil {
+		s.recordMethodStatus(service, method, nil)
 		return true
 	}
 
@@ -711,6 +970,7 @@ func (s *Scanner) checkMethod(ctx context.Context, service, method string) bool
 	case codes.InvalidArgument, codes.FailedPrecondition,
 		codes.Unauthenticated, codes.PermissionDenied,
 		codes.Internal:
+		s.recordMethodStatus(service, method, err)
 		return true
 	}
 
@@ -720,11 +980,10 @@ func (s *Scanner) checkMethod(ctx context.Context, service, method string) bool
 // Thread-safe result updates
 func (s *Scanner) addService(service, source string) {
 	s.resultMutex.Lock()
-	defer s.resultMutex.Unlock()
-
 	// Check if already exists
 	for _, existing := range s.result.AvailableServices {
 		if existing == service {
+			s.resultMutex.Unlock()
 			return
 		}
 	}
@@ -734,6 +993,10 @@ func (s *Scanner) addService(service, source string) {
 		// Don't print for reflection/standard as they're shown differently
 		fmt.Printf("[+] Found: %s\n", service)
 	}
+	s.resultMutex.Unlock()
+
+	// Catch health changes from the moment a service is known
+	s.watchService(service)
 }
 
 func (s *Scanner) addMethod(service, method string) {
@@ -775,6 +1038,12 @@ func (s *Scanner) PrintResults() {
 	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 	fmt.Printf("Target:          %s\n", s.result.Target)
 	fmt.Printf("Discovery Mode:  %s\n", s.result.ScanMode)
+	if s.result.Endpoint != nil {
+		fmt.Printf("Endpoint:        %s\n", s.result.Endpoint.Describe())
+	}
+	if s.result.Transport != "" && s.result.Transport != transportNative {
+		fmt.Printf("Transport:       %s\n", s.result.Transport)
+	}
 	fmt.Printf("Services Found:  %d\n", len(s.result.AvailableServices))
 
 	if s.result.ReflectionEnabled {
@@ -783,21 +1052,79 @@ func (s *Scanner) PrintResults() {
 		fmt.Printf("Reflection:      Disabled\n")
 	}
 
+	if health, ok := s.result.HealthStatus[""]; ok {
+		fmt.Printf("Server Health:   %s\n", health)
+	}
+
 	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n\n")
 
 	fmt.Println("Discovered Services:")
 	for _, service := range s.result.AvailableServices {
 		fmt.Printf("\n%s\n", service)
+		if health, ok := s.result.HealthStatus[service]; ok {
+			fmt.Printf("   Health: %s\n", health)
+		}
 		if methods, ok := s.result.MethodsFound[service]; ok && len(methods) > 0 {
 			fmt.Printf("   Methods (%d):\n", len(methods))
 			for _, method := range methods {
 				fmt.Printf("   └─ %s\n", method)
+				if probe, ok := s.result.MethodStatus[service+"/"+method]; ok && len(probe.Details) > 0 {
+					printStatusDetails(probe.Details, "      ")
+				}
 			}
 		} else {
 			fmt.Printf("   Methods: None confirmed\n")
 		}
 	}
 
+	if len(s.result.AdminServices) > 0 {
+		fmt.Println("\nExposed Admin/Debug Services:")
+		for _, admin := range s.result.AdminServices {
+			fmt.Printf("   [%s] %s - %s\n", strings.ToUpper(admin.Severity), admin.Service, admin.Description)
+		}
+	}
+
+	if cz := s.result.Channelz; cz != nil {
+		fmt.Println("\nChannelz Internals:")
+		for _, server := range cz.Servers {
+			fmt.Printf("   Server:  %s\n", server)
+		}
+		for _, channel := range cz.Channels {
+			fmt.Printf("   Channel: %s\n", channel)
+		}
+		for _, socket := range cz.Sockets {
+			fmt.Printf("   Socket %d: %s -> %s", socket.ID, socket.Local, socket.Remote)
+			if socket.TLS != "" {
+				fmt.Printf(" [%s]", socket.TLS)
+			}
+			if len(socket.PeerCert) > 0 {
+				fmt.Printf(" peer: %s", strings.Join(socket.PeerCert, ", "))
+			}
+			fmt.Println()
+		}
+	}
+
+	s.printAuthMatrix()
+	s.printBypassAttempts()
+	s.printJWTResults()
+	s.printSprayResult()
+	s.printRequestSkeletons()
+	s.printGatewayRoutes()
+	s.printResponseMetadata()
+	s.printErrorMessages()
+	s.printFindings()
+
+	if len(s.result.HealthEvents) > 0 {
+		fmt.Println("\nHealth Changes During Scan:")
+		for _, event := range s.result.HealthEvents {
+			name := event.Service
+			if name == "" {
+				name = "(server)"
+			}
+			fmt.Printf("   %s  %s: %s -> %s\n", event.Timestamp, name, event.Previous, event.Status)
+		}
+	}
+
 	fmt.Printf("\n━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
 }
 
@@ -822,9 +1149,8 @@ func (s *Scanner) SaveResults(filename string) {
 	}
 }
 
-// handleDirectCall handles the -call flag for direct method invocation
-func handleDirectCall(target, call string, timeout time.Duration, verbose bool) {
-	// Parse the call format (Service/Method or Service.Method)
+// parseCallTarget splits a Service/Method or Service.Method string
+func parseCallTarget(call string) (string, string, error) {
 	var service, method string
 	if strings.Contains(call, "/") {
 		parts := strings.SplitN(call, "/", 2)
@@ -838,36 +1164,100 @@ func handleDirectCall(target, call string, timeout time.Duration, verbose bool)
 		service = call[:lastDot]
 		method = call[lastDot+1:]
 	} else {
-		log.Fatalf("Invalid call format. Use Service/Method or Service.Method")
+		return "", "", fmt.Errorf("Invalid call format. Use Service/Method or Service.Method")
 	}
 
 	if method == "" {
-		log.Fatalf("Method name is required in call format")
+		return "", "", fmt.Errorf("Method name is required in call format")
+	}
+	return service, method, nil
+}
+
+// handleDirectCall handles the -call flag for direct method invocation
+func handleDirectCall(target, call string, timeout time.Duration, verbose bool, output, transport string, useTLS bool, replay string) {
+	service, method, err := parseCallTarget(call)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	var captured *CapturedCall
+	if replay != "" {
+		calls, err := loadCapturedCalls(replay)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		var count int
+		if captured, count = findCapturedCall(calls, service, method); captured == nil {
+			log.Fatalf("No captured request for %s/%s in %s", service, method, replay)
+		}
+		// Replay under the captured package-qualified name
+		service, _ = splitFullMethod(captured.Method)
+		if len(captured.Messages) > 1 {
+			fmt.Printf("[+] Replaying captured %s stream (%d messages, %d metadata keys, 1 of %d captured)\n",
+				captured.Codec, len(captured.Messages), len(captured.Metadata), count)
+		} else {
+			fmt.Printf("[+] Replaying captured %s request (%d bytes, %d metadata keys, 1 of %d captured)\n",
+				captured.Codec, len(captured.Payload), len(captured.Metadata), count)
+		}
 	}
 
 	// Connect to the server
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, used, err := dialTransport(ctx, target, transport, useTLS)
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
 	defer conn.Close()
 
 	fmt.Printf("[+] Testing %s/%s on %s\n", service, method, target)
+	if used != transportNative {
+		fmt.Printf("[+] Using %s transport\n", used)
+	}
 
 	// Try to invoke the method
 	fullMethod := fmt.Sprintf("/%s/%s", service, method)
-	err = conn.Invoke(ctx, fullMethod, nil, nil)
+	var header, trailer metadata.MD
+	if captured != nil {
+		if captured.Codec == "json" && used != transportConnectJSON {
+			fmt.Printf("[!] Captured request is JSON; the server may only accept it with -transport=connect-json\n")
+		}
+		err = replayCapturedCall(ctx, conn, fullMethod, captured, grpc.Header(&header), grpc.Trailer(&trailer))
+	} else {
+		err = invokeEmpty(ctx, conn, fullMethod, grpc.Header(&header), grpc.Trailer(&trailer))
+	}
+
+	st := status.Convert(err)
+	result := CallResult{
+		Target:    target,
+		Method:    service + "/" + method,
+		Code:      st.Code().String(),
+		Message:   st.Message(),
+		Details:   decodeStatusDetails(st),
+		Headers:   header,
+		Trailers:  trailer,
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if output != "" {
+		defer saveCallResult(output, result)
+	}
+	if verbose {
+		printMetadataSection("Header", header)
+		printMetadataSection("Trailer", trailer)
+	}
 
 	if err == nil {
-		fmt.Printf("[+] Success: Method exists (may require proper request message)\n")
+		if captured != nil {
+			fmt.Printf("[+] Success: Captured request accepted\n")
+		} else {
+			fmt.Printf("[+] Success: Method exists (may require proper request message)\n")
+		}
 		return
 	}
 
 	// Analyze the error
-	if st, ok := status.FromError(err); ok {
+	if _, ok := status.FromError(err); ok {
 		switch st.Code() {
 		case codes.Unimplemented:
 			if strings.Contains(strings.ToLower(st.Message()), "unknown service") {
@@ -892,11 +1282,27 @@ func handleDirectCall(target, call string, timeout time.Duration, verbose bool)
 		default:
 			fmt.Printf("[?] Error: %v (code: %v)\n", st.Message(), st.Code())
 		}
+		if len(result.Details) > 0 {
+			fmt.Printf("    Details:\n")
+			printStatusDetails(result.Details, "      ")
+		}
 	} else {
 		fmt.Printf("[-] Non-gRPC error: %v\n", err)
 	}
 }
 
+// saveCallResult writes a -call result as JSON
+func saveCallResult(filename string, result CallResult) {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to marshal results: %v", err)
+	}
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		log.Fatalf("Failed to save results: %v", err)
+	}
+}
+
 // handleDirectTesting handles the -service and -method flags
 func (s *Scanner) handleDirectTesting(services, methods string) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
@@ -905,12 +1311,10 @@ func (s *Scanner) handleDirectTesting(services, methods string) {
 	fmt.Printf("[+] Direct testing on %s...\n", s.target)
 
 	// Connect to server
-	conn, err := grpc.DialContext(ctx, s.target, grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		log.Fatalf("Failed to connect: %v", err)
+	if err := s.connect(ctx); err != nil {
+		log.Fatalf("%v", err)
 	}
-	defer conn.Close()
-	s.conn = conn
+	defer s.conn.Close()
 
 	// Wait for connection
 	if !s.waitForConnection(ctx) {
@@ -977,4 +1381,20 @@ func (s *Scanner) handleDirectTesting(services, methods string) {
 			fmt.Printf("   [-] Service '%s' not found\n", service)
 		}
 	}
+
+	if s.authMatrix {
+		s.runAuthMatrix()
+	}
+	if s.bypass {
+		s.runBypassProbes()
+	}
+	if s.jwtTests {
+		s.runJWTTests()
+	}
+	if s.harvest {
+		s.runHarvest()
+	}
+	if s.gateway {
+		s.runGateway()
+	}
 }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// statusDetailsKey is the trailer carrying the google.rpc.Status details
+const statusDetailsKey = "grpc-status-details-bin"
+
+// maxMetadataValues caps how many distinct values are kept per header name,
+// since timing and request-id headers change on every call
+const maxMetadataValues = 5
+
+// ResponseMetadata aggregates distinct response header and trailer values
+type ResponseMetadata struct {
+	Headers  map[string][]string `json:"headers,omitempty"`
+	Trailers map[string][]string `json:"trailers,omitempty"`
+}
+
+// Response header names that fingerprint the server stack or leak debug data
+var (
+	fingerprintHeaderPattern = regexp.MustCompile(`^(server|x-powered-by|via|x-envoy-.*|x-served-by|x-backend.*|x-upstream.*|x-.*version.*|x-runtime|x-aspnet.*|x-amzn-.*|x-cloud-trace-context|x-goog-.*)$`)
+	debugHeaderPattern       = regexp.MustCompile(`^x-.*(debug|trace|internal|stack|host|node|pod|instance|upstream-addr)`)
+)
+
+// recordResponseMetadata merges the headers and trailers of one call
+func (s *Scanner) recordResponseMetadata(header, trailer metadata.MD) {
+	if len(header) == 0 && len(trailer) == 0 {
+		return
+	}
+
+	s.resultMutex.Lock()
+	defer s.resultMutex.Unlock()
+
+	if s.result.ResponseMetadata == nil {
+		s.result.ResponseMetadata = &ResponseMetadata{
+			Headers:  make(map[string][]string),
+			Trailers: make(map[string][]string),
+		}
+	}
+	mergeMetadata(s.result.ResponseMetadata.Headers, header)
+	mergeMetadata(s.result.ResponseMetadata.Trailers, trailer)
+}
+
+// recordCall records the status message and response metadata of one call.
+// gRPC clients strip grpc-status-details-bin from the trailers, so it is
+// restored from the call status as the code and the detail types it carried.
+func (s *Scanner) recordCall(path string, header, trailer metadata.MD, err error) {
+	s.recordErrorMessage(path, err)
+	if st, ok := status.FromError(err); ok && err != nil {
+		if details := decodeStatusDetails(st); len(details) > 0 {
+			types := make([]string, 0, len(details))
+			for _, detail := range details {
+				types = append(types, detail.Type)
+			}
+			trailer = trailer.Copy()
+			trailer.Set(statusDetailsKey, fmt.Sprintf("%s: %s", st.Code(), strings.Join(types, ", ")))
+		}
+	}
+	s.recordResponseMetadata(header, trailer)
+}
+
+// recordingConn records the status and response metadata of every call the
+// generated clients (health, reflection, channelz) make through it
+type recordingConn struct {
+	s    *Scanner
+	conn grpc.ClientConnInterface
+}
+
+// recording returns the scanner connection wrapped in a recordingConn
+func (s *Scanner) recording() grpc.ClientConnInterface {
+	return recordingConn{s: s, conn: s.conn}
+}
+
+func (c recordingConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...grpc.CallOption) error {
+	var header, trailer metadata.MD
+	opts = append(opts, grpc.Header(&header), grpc.Trailer(&trailer))
+	err := c.conn.Invoke(ctx, method, args, reply, opts...)
+	c.s.recordCall(method, header, trailer, err)
+	return err
+}
+
+func (c recordingConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := c.conn.NewStream(ctx, desc, method, opts...)
+	if err != nil {
+		c.s.recordCall(method, nil, nil, err)
+		return nil, err
+	}
+	return &recordingStream{ClientStream: stream, s: c.s, method: method}, nil
+}
+
+// recordingStream records the header with the first response message and
+// the trailer and status when the stream ends
+type recordingStream struct {
+	grpc.ClientStream
+	s          *Scanner
+	method     string
+	headerSeen bool
+}
+
+func (r *recordingStream) RecvMsg(m interface{}) error {
+	err := r.ClientStream.RecvMsg(m)
+
+	var header metadata.MD
+	if !r.headerSeen {
+		r.headerSeen = true
+		header, _ = r.ClientStream.Header()
+	}
+	if err == nil {
+		r.s.recordResponseMetadata(header, nil)
+		return nil
+	}
+
+	callErr := err
+	if err == io.EOF {
+		callErr = nil
+	}
+	r.s.recordCall(r.method, header, r.ClientStream.Trailer(), callErr)
+	return err
+}
+
+// mergeMetadata adds distinct values from md into seen
+func mergeMetadata(seen map[string][]string, md metadata.MD) {
+	for name, values := range md {
+		for _, value := range values {
+			// grpc-status-details-bin is already decoded by recordCall
+			if strings.HasSuffix(name, "-bin") && name != statusDetailsKey {
+				value = fmt.Sprintf("<%d bytes binary>", len(value))
+			}
+			if len(seen[name]) >= maxMetadataValues || containsString(seen[name], value) {
+				continue
+			}
+			seen[name] = append(seen[name], value)
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeResponseMetadata reports fingerprinting and debug headers
+func (s *Scanner) analyzeResponseMetadata() {
+	md := s.result.ResponseMetadata
+	if md == nil {
+		return
+	}
+
+	for _, section := range []struct {
+		kind   string
+		values map[string][]string
+	}{{"header", md.Headers}, {"trailer", md.Trailers}} {
+		for _, name := range sortedMetadataNames(section.values) {
+			evidence := fmt.Sprintf("%s %s: %s", section.kind, name, strings.Join(section.values[name], ", "))
+			switch {
+			case debugHeaderPattern.MatchString(name):
+				s.addFinding(Finding{
+					ID:          "response-debug-metadata",
+					Title:       "Response metadata exposes debug or internal information",
+					Severity:    "low",
+					Evidence:    evidence,
+					Remediation: "Strip debug and internal routing metadata at the edge proxy.",
+				})
+			case fingerprintHeaderPattern.MatchString(name):
+				s.addFinding(Finding{
+					ID:          "response-metadata-fingerprint",
+					Title:       "Response metadata fingerprints the server stack",
+					Severity:    "info",
+					Evidence:    evidence,
+					Remediation: "Remove server, proxy and version headers from responses.",
+				})
+			}
+		}
+	}
+}
+
+func sortedMetadataNames(values map[string][]string) []string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printResponseMetadata lists the aggregated headers and trailers in verbose output
+func (s *Scanner) printResponseMetadata() {
+	md := s.result.ResponseMetadata
+	if !s.verbose || md == nil {
+		return
+	}
+
+	fmt.Println("\nResponse Metadata:")
+	printMetadataSection("Header", md.Headers)
+	printMetadataSection("Trailer", md.Trailers)
+}
+
+func printMetadataSection(kind string, values map[string][]string) {
+	for _, name := range sortedMetadataNames(values) {
+		fmt.Printf("   %-8s %s: %s\n", kind, name, strings.Join(values[name], ", "))
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// maxArchiveEntry caps how much of one archive member is read
+const maxArchiveEntry = 64 << 20
+
+var (
+	// "/acme.user.v1.UserService/GetUser" as a complete string constant
+	// (Square Wire, grpc-swift, hand-written clients)
+	binMethodPathPattern = regexp.MustCompile(`^/((?:[A-Za-z_]\w*\.)*[A-Z]\w*)/([A-Z]\w*)$`)
+	// "acme.user.v1.UserService", the SERVICE_NAME constant of a grpc-java stub
+	binServiceNamePattern = regexp.MustCompile(`^(?:[a-z_]\w*\.)+[A-Z]\w*$`)
+	// Lacme/user/v1/UserServiceGrpc; type descriptor of a grpc-java stub class
+	dexGrpcClassPattern = regexp.MustCompile(`^L(?:[\w$]+/)*(\w+)Grpc;$`)
+	// getGetUserMethod(), the MethodDescriptor getter grpc-java generates per rpc
+	dexMethodGetterPattern = regexp.MustCompile(`^get(\w+)Method$`)
+)
+
+// extractAPKStubs scans an APK (or IPA) for generated gRPC stubs: DEX
+// string and method tables, Mach-O executables, bundled .proto files and
+// serialized FileDescriptorProtos
+func extractAPKStubs(filename string) ([]protoService, error) {
+	archive, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer archive.Close()
+
+	var services []protoService
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || file.UncompressedSize64 > maxArchiveEntry {
+			continue
+		}
+		name := strings.ToLower(file.Name)
+		ext := path.Ext(name)
+		isDex := ext == ".dex"
+		// Executables inside an IPA sit in Payload/<App>.app/ without an extension
+		maybeMachO := strings.HasPrefix(name, "payload/") && (ext == "" || ext == ".dylib")
+		if !isDex && !maybeMachO && ext != ".proto" && !descriptorSetExts[ext] {
+			continue
+		}
+
+		data, err := readZipFile(file)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case isDex:
+			found, err := extractDexStubs(data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", file.Name, err)
+			}
+			services = append(services, found...)
+		case maybeMachO:
+			if isMachO(data) {
+				found, err := extractMachOStubs(data)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", file.Name, err)
+				}
+				services = append(services, found...)
+			}
+		case ext == ".proto":
+			_, found := parseProtoFile(string(data))
+			services = append(services, found...)
+		default:
+			services = append(services, scanEmbeddedDescriptors(data)...)
+		}
+	}
+	return services, nil
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", file.Name, err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxArchiveEntry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", file.Name, err)
+	}
+	return data, nil
+}
+
+// extractDexStubs reads the string, type and method tables of a DEX file.
+// grpc-java compiles each service to a <Service>Grpc class holding the
+// SERVICE_NAME string and a get<Method>Method() getter per rpc.
+func extractDexStubs(data []byte) ([]protoService, error) {
+	if len(data) < 0x70 || !bytes.HasPrefix(data, []byte("dex\n")) {
+		return nil, fmt.Errorf("not a DEX file")
+	}
+	le := binary.LittleEndian
+	stringsSize, stringsOff := le.Uint32(data[0x38:]), le.Uint32(data[0x3C:])
+	typesSize, typesOff := le.Uint32(data[0x40:]), le.Uint32(data[0x44:])
+	methodsSize, methodsOff := le.Uint32(data[0x58:]), le.Uint32(data[0x5C:])
+	if uint64(stringsOff)+uint64(stringsSize)*4 > uint64(len(data)) ||
+		uint64(typesOff)+uint64(typesSize)*4 > uint64(len(data)) ||
+		uint64(methodsOff)+uint64(methodsSize)*8 > uint64(len(data)) {
+		return nil, fmt.Errorf("truncated DEX file")
+	}
+
+	dexStrings := make([]string, stringsSize)
+	for i := range dexStrings {
+		dexStrings[i] = dexString(data, le.Uint32(data[stringsOff+uint32(i)*4:]))
+	}
+	typeName := func(idx uint32) string {
+		if idx >= typesSize {
+			return ""
+		}
+		if strIdx := le.Uint32(data[typesOff+idx*4:]); strIdx < stringsSize {
+			return dexStrings[strIdx]
+		}
+		return ""
+	}
+
+	// Full names by short name, from SERVICE_NAME constants
+	fullNames := make(map[string]string)
+	var services []protoService
+	for _, s := range dexStrings {
+		if binServiceNamePattern.MatchString(s) {
+			fullNames[s[strings.LastIndex(s, ".")+1:]] = s
+		}
+		if match := binMethodPathPattern.FindStringSubmatch(s); match != nil {
+			services = append(services, splitServiceName(match[1], match[2]))
+		}
+		if latin1, ok := mutf8Latin1(s); ok && strings.Contains(latin1, ".proto") {
+			services = append(services, scanEmbeddedDescriptors([]byte(latin1))...)
+		}
+	}
+
+	methods := make(map[string][]string)
+	var order []string
+	for i := uint32(0); i < methodsSize; i++ {
+		item := data[methodsOff+i*8:]
+		class := dexGrpcClassPattern.FindStringSubmatch(typeName(uint32(le.Uint16(item))))
+		nameIdx := le.Uint32(item[4:])
+		if class == nil || nameIdx >= stringsSize {
+			continue
+		}
+		getter := dexMethodGetterPattern.FindStringSubmatch(dexStrings[nameIdx])
+		if getter == nil {
+			continue
+		}
+		if _, seen := methods[class[1]]; !seen {
+			order = append(order, class[1])
+		}
+		methods[class[1]] = append(methods[class[1]], getter[1])
+	}
+	for _, short := range order {
+		fullName := fullNames[short]
+		if fullName == "" {
+			fullName = short
+		}
+		services = append(services, splitServiceName(fullName, methods[short]...))
+	}
+	return services, nil
+}
+
+// dexString decodes the string_data_item at off: a ULEB128 length in UTF-16
+// units followed by NUL-terminated MUTF-8
+func dexString(data []byte, off uint32) string {
+	if uint64(off) >= uint64(len(data)) {
+		return ""
+	}
+	_, n := binary.Uvarint(data[off:])
+	if n <= 0 {
+		return ""
+	}
+	start := int(off) + n
+	end := bytes.IndexByte(data[start:], 0)
+	if end < 0 {
+		return ""
+	}
+	return string(data[start : start+end])
+}
+
+// mutf8Latin1 converts MUTF-8 back to the bytes a Java string of chars
+// 0-255 stands for, which is how protoc embeds descriptors in Java sources
+func mutf8Latin1(s string) (string, bool) {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c < 0x80:
+			out = append(out, c)
+			i++
+		case c&0xE0 == 0xC0 && i+1 < len(s):
+			r := uint16(c&0x1F)<<6 | uint16(s[i+1]&0x3F)
+			if r > 0xFF {
+				return "", false
+			}
+			out = append(out, byte(r))
+			i += 2
+		default:
+			return "", false
+		}
+	}
+	return string(out), true
+}
+
+// isMachO reports whether data starts with a Mach-O or universal binary magic
+func isMachO(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	switch binary.BigEndian.Uint32(data) {
+	case macho.Magic32, macho.Magic64, macho.MagicFat, 0xCEFAEDFE, 0xCFFAEDFE:
+		return true
+	}
+	return false
+}
+
+// extractMachOStubs scans the sections of a Mach-O binary (every slice of a
+// universal binary) for method path strings and embedded descriptors
+func extractMachOStubs(data []byte) ([]protoService, error) {
+	var files []*macho.File
+	if fat, err := macho.NewFatFile(bytes.NewReader(data)); err == nil {
+		for _, arch := range fat.Arches {
+			files = append(files, arch.File)
+		}
+	} else {
+		file, err := macho.NewFile(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Mach-O binary: %v", err)
+		}
+		files = append(files, file)
+	}
+
+	var services []protoService
+	for _, file := range files {
+		for _, section := range file.Sections {
+			if section.Flags&0xFF == 0x01 { // S_ZEROFILL
+				continue
+			}
+			raw, err := section.Data()
+			if err != nil {
+				continue
+			}
+			for _, s := range binaryStrings(raw) {
+				if match := binMethodPathPattern.FindStringSubmatch(s); match != nil {
+					services = append(services, splitServiceName(match[1], match[2]))
+				}
+			}
+			services = append(services, scanEmbeddedDescriptors(raw)...)
+		}
+	}
+	return services, nil
+}
+
+// binaryStrings returns the runs of printable ASCII of at least 4 bytes
+func binaryStrings(data []byte) []string {
+	var out []string
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] >= 0x20 && data[i] < 0x7F {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= 4 {
+			out = append(out, string(data[start:i]))
+		}
+		start = -1
+	}
+	return out
+}
+
+// scanEmbeddedDescriptors finds serialized FileDescriptorProtos inside
+// arbitrary bytes. A descriptor starts with field 1, the file name ending in
+// ".proto"; it runs for as long as the bytes parse as valid fields.
+func scanEmbeddedDescriptors(data []byte) []protoService {
+	var services []protoService
+	for offset := 0; ; {
+		pos := bytes.Index(data[offset:], []byte(".proto"))
+		if pos < 0 {
+			break
+		}
+		pos += offset
+		offset = pos + 1
+
+		// Look back for the 0x0A tag and a length that ends right here
+		for nameLen := 1; nameLen <= 120 && pos+6-nameLen-2 >= 0; nameLen++ {
+			start := pos + 6 - nameLen - 2
+			if data[start] != 0x0A || int(data[start+1]) != nameLen {
+				continue
+			}
+			end := descriptorEnd(data[start:])
+			file := &descriptorpb.FileDescriptorProto{}
+			if proto.Unmarshal(data[start:start+end], file) == nil {
+				services = append(services, descriptorServices(file)...)
+				offset = start + end
+			}
+			break
+		}
+	}
+	return services
+}
+
+// descriptorEnd returns how many leading bytes parse as FileDescriptorProto
+// fields
+func descriptorEnd(data []byte) int {
+	end := 0
+	for end < len(data) {
+		num, typ, n := protowire.ConsumeTag(data[end:])
+		if n < 0 || num < 1 || num > 15 {
+			break
+		}
+		// Every field of FileDescriptorProto is length-delimited except the
+		// repeated int32 dependency indexes (10, 11) and the edition enum
+		if typ != protowire.BytesType && !(typ == protowire.VarintType && (num == 10 || num == 11 || num == 14)) {
+			break
+		}
+		m := protowire.ConsumeFieldValue(num, typ, data[end+n:])
+		if m < 0 {
+			break
+		}
+		end += n + m
+	}
+	return end
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// openAPISpec is the part of an OpenAPI 2 (swagger) or 3 document the
+// scanner reads
+type openAPISpec struct {
+	Swagger  string                                `json:"swagger"`
+	OpenAPI  string                                `json:"openapi"`
+	BasePath string                                `json:"basePath"`
+	APIName  string                                `json:"x-google-api-name"`
+	Paths    map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// openAPIOperation is one operation mapped back to a gRPC method
+type openAPIOperation struct {
+	Service     string
+	Method      string
+	HTTPMethod  string
+	Path        string
+	OperationID string
+}
+
+// HTTP methods that can appear as operations in a path item
+var openAPIHTTPMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+// parseOpenAPI decodes an OpenAPI JSON or YAML document
+func parseOpenAPI(data []byte) (*openAPISpec, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] != '{' {
+		var doc interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAPI document: %v", err)
+		}
+		converted, err := json.Marshal(jsonCompatible(doc))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse OpenAPI document: %v", err)
+		}
+		data = converted
+	}
+
+	var spec openAPISpec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI document: %v", err)
+	}
+	if spec.Swagger == "" && spec.OpenAPI == "" {
+		return nil, fmt.Errorf("not an OpenAPI document: missing swagger/openapi version")
+	}
+	return &spec, nil
+}
+
+// jsonCompatible turns the map[interface{}]interface{} values YAML produces
+// for non-string keys (such as response codes) into JSON objects
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = jsonCompatible(value)
+		}
+		return v
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = jsonCompatible(value)
+		}
+		return v
+	}
+	return v
+}
+
+// openAPIOperations lists the operations of a spec. protoc-gen-openapiv2
+// names operations "Service_Method" and tags them with the service, which is
+// package-qualified with include_package_in_tags; otherwise the first tag
+// (or the Cloud Endpoints x-google-api-name) is used as the service and the
+// operationId as the method.
+func openAPIOperations(spec *openAPISpec) []openAPIOperation {
+	var ops []openAPIOperation
+	for path, item := range spec.Paths {
+		for _, httpMethod := range openAPIHTTPMethods {
+			raw, ok := item[httpMethod]
+			if !ok {
+				continue
+			}
+			var op struct {
+				OperationID string   `json:"operationId"`
+				Tags        []string `json:"tags"`
+			}
+			if json.Unmarshal(raw, &op) != nil {
+				continue
+			}
+
+			var service, method string
+			if pos := strings.LastIndex(op.OperationID, "_"); pos > 0 {
+				service, method = op.OperationID[:pos], op.OperationID[pos+1:]
+				if len(op.Tags) > 0 && strings.HasSuffix(op.Tags[0], "."+service) {
+					service = op.Tags[0]
+				}
+			} else if op.OperationID != "" {
+				service, method = spec.APIName, upperFirst(op.OperationID)
+				if len(op.Tags) > 0 {
+					service = op.Tags[0]
+				}
+			}
+			if service == "" || method == "" {
+				continue
+			}
+
+			ops = append(ops, openAPIOperation{
+				Service:     service,
+				Method:      method,
+				HTTPMethod:  strings.ToUpper(httpMethod),
+				Path:        strings.TrimSuffix(spec.BasePath, "/") + path,
+				OperationID: op.OperationID,
+			})
+		}
+	}
+
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return ops[i].HTTPMethod < ops[j].HTTPMethod
+	})
+	return ops
+}
+
+func upperFirst(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
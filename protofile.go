@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// protoService is a service declared in a .proto file or descriptor
+type protoService struct {
+	Package string
+	Name    string
+	Methods []string
+}
+
+// FullName returns the package-qualified service name
+func (p protoService) FullName() string {
+	if p.Package == "" {
+		return p.Name
+	}
+	return p.Package + "." + p.Name
+}
+
+// parseProtoFile extracts the package and the services with their rpcs from
+// .proto source. It only understands as much of the grammar as it needs:
+// comments, strings and brace nesting are skipped, message bodies ignored.
+func parseProtoFile(src string) (string, []protoService) {
+	tokens := tokenizeProto(src)
+
+	var (
+		pkg      string
+		services []protoService
+		depth    int
+		current  = -1 // index into services while inside a service body
+		bodyAt   int  // depth of the current service body
+	)
+	next := func(i int) string {
+		if i+1 < len(tokens) {
+			return tokens[i+1]
+		}
+		return ""
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		switch tok := tokens[i]; tok {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if current >= 0 && depth < bodyAt {
+				current = -1
+			}
+		case "package":
+			// "package foo.bar;" at the top level, not a field named package
+			if depth == 0 && isProtoIdent(next(i)) && i+2 < len(tokens) && tokens[i+2] == ";" {
+				pkg = next(i)
+				i += 2
+			}
+		case "service":
+			if current < 0 && isProtoIdent(next(i)) && i+2 < len(tokens) && tokens[i+2] == "{" {
+				services = append(services, protoService{Name: next(i)})
+				current = len(services) - 1
+				depth++
+				bodyAt = depth
+				i += 2
+			}
+		case "rpc":
+			if current >= 0 && depth == bodyAt && isProtoIdent(next(i)) {
+				services[current].Methods = append(services[current].Methods, next(i))
+				i++
+			}
+		}
+	}
+
+	for i := range services {
+		services[i].Package = pkg
+	}
+	return pkg, services
+}
+
+// tokenizeProto splits .proto source into identifiers (dots included) and
+// single punctuation characters, dropping comments and string literals
+func tokenizeProto(src string) []string {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end + 1
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			i = j + 1
+		case c == '_' || c == '.' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == '.' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		case unicode.IsSpace(rune(c)):
+			i++
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+func isProtoIdent(tok string) bool {
+	if tok == "" || !(tok[0] == '_' || unicode.IsLetter(rune(tok[0]))) {
+		return false
+	}
+	return !strings.HasPrefix(tok, ".") && !strings.HasSuffix(tok, ".")
+}
+
+// parseDescriptorSet decodes a binary FileDescriptorSet (protoc
+// --descriptor_set_out, buf build -o) and lists its services
+func parseDescriptorSet(data []byte) ([]protoService, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("failed to parse FileDescriptorSet: %v", err)
+	}
+	if len(set.GetFile()) == 0 {
+		return nil, fmt.Errorf("not a FileDescriptorSet: no files")
+	}
+
+	var services []protoService
+	for _, file := range set.GetFile() {
+		if !strings.HasSuffix(file.GetName(), ".proto") {
+			return nil, fmt.Errorf("not a FileDescriptorSet: unexpected file name %q", file.GetName())
+		}
+		services = append(services, descriptorServices(file)...)
+	}
+	return services, nil
+}
+
+// descriptorServices lists the services of a FileDescriptorProto
+func descriptorServices(file *descriptorpb.FileDescriptorProto) []protoService {
+	var services []protoService
+	for _, svc := range file.GetService() {
+		service := protoService{Package: file.GetPackage(), Name: svc.GetName()}
+		for _, method := range svc.GetMethod() {
+			service.Methods = append(service.Methods, method.GetName())
+		}
+		services = append(services, service)
+	}
+	return services
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata keys read by common gRPC auth interceptors
+var defaultSprayKeys = []string{"x-api-key", "authorization", "x-auth-token"}
+
+// SprayResult summarizes a credential spraying run against one method
+type SprayResult struct {
+	Method   string     `json:"method"`
+	Baseline string     `json:"baseline"`
+	Attempts int        `json:"attempts"`
+	Aborted  string     `json:"aborted,omitempty"`
+	Hits     []SprayHit `json:"hits,omitempty"`
+}
+
+// SprayHit is a credential whose status differed from the anonymous baseline
+type SprayHit struct {
+	Key        string `json:"key"`
+	Credential string `json:"credential"`
+	Code       string `json:"code"`
+	Accepted   bool   `json:"accepted"`
+}
+
+// loadSprayList reads candidate credentials, one per line, skipping comments
+func loadSprayList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var creds []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		creds = append(creds, line)
+	}
+	return creds, scanner.Err()
+}
+
+// sprayValue formats a credential for a metadata key, adding a Bearer scheme
+// to bare tokens sent in the authorization header
+func sprayValue(key, credential string) string {
+	if key == "authorization" && !strings.Contains(credential, " ") {
+		return "Bearer " + credential
+	}
+	return credential
+}
+
+// handleSpray tests candidate credentials against one protected method,
+// honouring the attempt cap, per-attempt delay and stop-on-success settings
+func (s *Scanner) handleSpray(call, listPath string, keys []string) {
+	service, method, err := parseCallTarget(call)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	fullMethod := service + "/" + method
+
+	creds, err := loadSprayList(listPath)
+	if err != nil {
+		log.Fatalf("Failed to load spray list: %v", err)
+	}
+	if len(creds) == 0 {
+		log.Fatalf("Spray list %s is empty", listPath)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	if err := s.connect(ctx); err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer s.conn.Close()
+
+	if !s.waitForConnection(ctx) {
+		log.Fatalf("Failed to establish gRPC connection")
+	}
+
+	try := func(cred Credential) codes.Code {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		return status.Code(s.invoke(withCredential(ctx, cred), service, method))
+	}
+
+	result := &SprayResult{Method: fullMethod}
+	s.result.Spray = result
+
+	baseline := try(Credential{})
+	result.Baseline = baseline.String()
+	if !isAuthRejection(baseline) {
+		result.Aborted = fmt.Sprintf("anonymous call returned %s, method is not protected", baseline)
+		fmt.Printf("[-] Spray aborted: %s\n", result.Aborted)
+		return
+	}
+
+	total := len(creds) * len(keys)
+	if total > s.sprayMax {
+		total = s.sprayMax
+	}
+	fmt.Printf("[+] Spraying %s with %d attempts (%d credentials x %d keys, cap %d, delay %s)\n",
+		fullMethod, total, len(creds), len(keys), s.sprayMax, s.sprayDelay)
+
+spray:
+	for _, credential := range creds {
+		for _, key := range keys {
+			if result.Attempts >= s.sprayMax {
+				result.Aborted = fmt.Sprintf("attempt cap of %d reached", s.sprayMax)
+				break spray
+			}
+			if result.Attempts > 0 && s.sprayDelay > 0 {
+				time.Sleep(s.sprayDelay)
+			}
+
+			code := try(Credential{Metadata: []string{key, sprayValue(key, credential)}})
+			result.Attempts++
+
+			// Back off entirely if the server starts rate limiting or locking out
+			if code == codes.ResourceExhausted {
+				result.Aborted = "server returned ResourceExhausted (rate limit or lockout)"
+				break spray
+			}
+
+			if code == baseline {
+				if s.verbose {
+					fmt.Printf("   [-] %s=%s: %s\n", key, credential, code)
+				}
+				continue
+			}
+
+			// A timeout or Unavailable changes the status too, but only a
+			// handler-level answer means the credential got past the auth check
+			hit := SprayHit{Key: key, Credential: credential, Code: code.String(), Accepted: reachedHandler(code)}
+			result.Hits = append(result.Hits, hit)
+			fmt.Printf("[+] %s=%s changed status: %s -> %s\n", key, credential, baseline, code)
+
+			if hit.Accepted {
+				s.addFinding(Finding{
+					ID:          "credential-spray-success",
+					Title:       "Candidate credential accepted",
+					Severity:    "high",
+					Method:      fullMethod,
+					Evidence:    fmt.Sprintf("%s: %s returned %s (anonymous: %s)", key, credential, code, baseline),
+					Remediation: "Rotate the credential, remove default or leaked keys and rate limit authentication failures.",
+				})
+				if s.sprayStop {
+					result.Aborted = "stopped on first success"
+					break spray
+				}
+			}
+		}
+	}
+
+	if result.Aborted != "" {
+		fmt.Printf("[*] Spray stopped after %d attempts: %s\n", result.Attempts, result.Aborted)
+	}
+}
+
+// printSprayResult summarizes a credential spraying run in the human output
+func (s *Scanner) printSprayResult() {
+	result := s.result.Spray
+	if result == nil {
+		return
+	}
+
+	fmt.Printf("\nCredential Spray: %s (baseline %s, %d attempts)\n", result.Method, result.Baseline, result.Attempts)
+	if len(result.Hits) == 0 {
+		fmt.Println("   No credential changed the status")
+	}
+	for _, hit := range result.Hits {
+		fmt.Printf("   %-14s %-30s %s\n", hit.Key, hit.Credential, hit.Code)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+// StatusDetail is one decoded entry of google.rpc.Status.details
+type StatusDetail struct {
+	Type    string          `json:"type"`
+	Text    []string        `json:"text,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Decoded bool            `json:"decoded"`
+}
+
+// CallResult is the JSON result of a -call invocation
+type CallResult struct {
+	Target    string              `json:"target"`
+	Method    string              `json:"method"`
+	Code      string              `json:"code"`
+	Message   string              `json:"message,omitempty"`
+	Details   []StatusDetail      `json:"details,omitempty"`
+	Headers   map[string][]string `json:"headers,omitempty"`
+	Trailers  map[string][]string `json:"trailers,omitempty"`
+	Timestamp string              `json:"timestamp"`
+}
+
+// decodeStatusDetails decodes every detail attached to a status. Well-known
+// google.rpc types are summarized field by field, other linked types are
+// rendered as text and unknown types are reported by their type URL.
+func decodeStatusDetails(st *status.Status) []StatusDetail {
+	var details []StatusDetail
+	for _, raw := range st.Proto().GetDetails() {
+		typeName := raw.GetTypeUrl()
+		if pos := strings.LastIndex(typeName, "/"); pos >= 0 {
+			typeName = typeName[pos+1:]
+		}
+
+		msg, err := raw.UnmarshalNew()
+		if err != nil {
+			details = append(details, StatusDetail{
+				Type: typeName,
+				Text: []string{fmt.Sprintf("undecoded %s (%d bytes)", raw.GetTypeUrl(), len(raw.GetValue()))},
+			})
+			continue
+		}
+
+		detail := StatusDetail{Type: typeName, Text: describeDetail(msg), Decoded: true}
+		if data, err := protojson.Marshal(msg); err == nil {
+			detail.Data = data
+		}
+		details = append(details, detail)
+	}
+	return details
+}
+
+// describeDetail renders a decoded detail message as human-readable lines
+func describeDetail(msg proto.Message) []string {
+	var lines []string
+	switch d := msg.(type) {
+	case *errdetails.ErrorInfo:
+		lines = append(lines, fmt.Sprintf("reason=%s domain=%s", d.GetReason(), d.GetDomain()))
+		keys := make([]string, 0, len(d.GetMetadata()))
+		for k := range d.GetMetadata() {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf("%s=%s", k, d.GetMetadata()[k]))
+		}
+	case *errdetails.RetryInfo:
+		lines = append(lines, fmt.Sprintf("retry after %s", d.GetRetryDelay().AsDuration()))
+	case *errdetails.DebugInfo:
+		if d.GetDetail() != "" {
+			lines = append(lines, d.GetDetail())
+		}
+		lines = append(lines, d.GetStackEntries()...)
+	case *errdetails.QuotaFailure:
+		for _, v := range d.GetViolations() {
+			lines = append(lines, fmt.Sprintf("%s: %s", v.GetSubject(), v.GetDescription()))
+		}
+	case *errdetails.PreconditionFailure:
+		for _, v := range d.GetViolations() {
+			lines = append(lines, fmt.Sprintf("%s %s: %s", v.GetType(), v.GetSubject(), v.GetDescription()))
+		}
+	case *errdetails.BadRequest:
+		for _, v := range d.GetFieldViolations() {
+			lines = append(lines, fmt.Sprintf("%s: %s", v.GetField(), v.GetDescription()))
+		}
+	case *errdetails.RequestInfo:
+		lines = append(lines, fmt.Sprintf("request_id=%s", d.GetRequestId()))
+		if d.GetServingData() != "" {
+			lines = append(lines, fmt.Sprintf("serving_data=%s", d.GetServingData()))
+		}
+	case *errdetails.ResourceInfo:
+		lines = append(lines, fmt.Sprintf("%s %s (owner %s): %s", d.GetResourceType(), d.GetResourceName(), d.GetOwner(), d.GetDescription()))
+	case *errdetails.Help:
+		for _, link := range d.GetLinks() {
+			lines = append(lines, fmt.Sprintf("%s: %s", link.GetDescription(), link.GetUrl()))
+		}
+	case *errdetails.LocalizedMessage:
+		lines = append(lines, fmt.Sprintf("[%s] %s", d.GetLocale(), d.GetMessage()))
+	default:
+		text := strings.TrimSpace(prototext.MarshalOptions{}.Format(msg))
+		if text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+// detailText flattens decoded details into "Type: line" strings
+func detailText(details []StatusDetail) []string {
+	var text []string
+	for _, detail := range details {
+		for _, line := range detail.Text {
+			text = append(text, detail.Type+": "+line)
+		}
+	}
+	return text
+}
+
+// printStatusDetails writes decoded details under a status line
+func printStatusDetails(details []StatusDetail, indent string) {
+	for _, detail := range details {
+		fmt.Printf("%s%s\n", indent, detail.Type)
+		for _, line := range detail.Text {
+			fmt.Printf("%s   %s\n", indent, line)
+		}
+	}
+}
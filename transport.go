@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// Conn is a connection the scanner can send calls through. *grpc.ClientConn
+// satisfies it, as do the HTTP-based transports, so generated clients
+// (health, reflection, channelz) work unchanged on any of them.
+type Conn interface {
+	grpc.ClientConnInterface
+	Close() error
+}
+
+// Transport names accepted by -transport
+const (
+	transportAuto        = "auto"
+	transportNative      = "native"
+	transportGRPCWeb     = "grpc-web"
+	transportGRPCWebText = "grpc-web-text"
+	transportConnect     = "connect"
+	transportConnectJSON = "connect-json"
+)
+
+var transportNames = []string{transportAuto, transportNative, transportGRPCWeb, transportGRPCWebText, transportConnect, transportConnectJSON}
+
+// httpFallbacks are tried in order when auto mode gets a plain HTTP response
+var httpFallbacks = []string{transportGRPCWeb, transportGRPCWebText, transportConnect}
+
+// validTransport reports whether name is a known transport
+func validTransport(name string) bool {
+	for _, known := range transportNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// newHTTPConn returns a connection for one of the HTTP-based transports
+func newHTTPConn(target, transport string, useTLS bool) Conn {
+	switch transport {
+	case transportGRPCWebText:
+		return newGRPCWebConn(target, true, useTLS)
+	case transportConnect:
+		return newConnectConn(target, false, useTLS)
+	case transportConnectJSON:
+		return newConnectConn(target, true, useTLS)
+	}
+	return newGRPCWebConn(target, false, useTLS)
+}
+
+// dialNative opens a regular HTTP/2 gRPC connection
+func dialNative(ctx context.Context, target string, useTLS bool) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	}
+	return grpc.DialContext(ctx, target, grpc.WithTransportCredentials(creds))
+}
+
+// dialTransport connects to target with the named transport. In auto mode a
+// native call is tried first; if it fails with an HTTP response instead of a
+// gRPC status, gRPC-Web (binary, then text) and Connect are tried. It
+// returns the connection and the transport actually used.
+func dialTransport(ctx context.Context, target, transport string, useTLS bool) (Conn, string, error) {
+	if transport != transportAuto && transport != transportNative {
+		return newHTTPConn(target, transport, useTLS), transport, nil
+	}
+
+	native, err := dialNative(ctx, target, useTLS)
+	if err != nil {
+		return nil, "", err
+	}
+	if transport == transportNative {
+		return native, transportNative, nil
+	}
+
+	if err := probeTransport(ctx, native); !looksLikeHTTPResponse(err) {
+		return native, transportNative, nil
+	}
+
+	for _, candidate := range httpFallbacks {
+		conn := newHTTPConn(target, candidate, useTLS)
+		if err := probeTransport(ctx, conn); err == nil || isGRPCStatus(err) {
+			native.Close()
+			return conn, candidate, nil
+		}
+		conn.Close()
+	}
+
+	return native, transportNative, nil
+}
+
+// probeTransport makes a health check call used to fingerprint a transport
+func probeTransport(ctx context.Context, conn grpc.ClientConnInterface) error {
+	return invokeEmpty(ctx, conn, "/grpc.health.v1.Health/Check")
+}
+
+// Fragments of errors produced when a call got an HTTP response that was not gRPC
+var httpResponseErrors = []string{
+	"unexpected HTTP status code",
+	"unexpected content-type",
+	"server preface",
+	"http2: frame too large",
+	"malformed HTTP",
+	"HTTP/1.",
+}
+
+// looksLikeHTTPResponse reports whether a call failed because the server
+// answered with plain HTTP rather than gRPC
+func looksLikeHTTPResponse(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := status.Convert(err).Message()
+	for _, fragment := range httpResponseErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGRPCStatus reports whether err is a status sent by a gRPC server rather
+// than a transport or HTTP failure. Deadline and cancellation errors are
+// raised by the client when the server never answers.
+func isGRPCStatus(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || looksLikeHTTPResponse(err) {
+		return false
+	}
+	if st.Code() == codes.DeadlineExceeded || st.Code() == codes.Canceled {
+		return false
+	}
+	return !strings.HasPrefix(st.Message(), "connection error") && !strings.HasPrefix(st.Message(), transportErrorPrefix)
+}
+
+// connect dials the scan target with the configured transport
+func (s *Scanner) connect(ctx context.Context) error {
+	conn, transport, err := dialTransport(ctx, s.target, s.transport, s.useTLS)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %v", err)
+	}
+	s.conn = conn
+	s.result.Transport = transport
+	if transport != transportNative {
+		fmt.Printf("[+] Using %s transport\n", transport)
+	}
+	return nil
+}
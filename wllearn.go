@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// learnedName counts the distinct targets a service, method or package
+// was confirmed on
+type learnedName struct {
+	name    string
+	targets map[string]bool
+}
+
+// nameCounter tallies learnedNames, keeping first-seen order for ties
+type nameCounter struct {
+	byName map[string]*learnedName
+	order  []*learnedName
+}
+
+func newNameCounter() *nameCounter {
+	return &nameCounter{byName: make(map[string]*learnedName)}
+}
+
+func (c *nameCounter) add(name, target string) {
+	n := c.byName[name]
+	if n == nil {
+		n = &learnedName{name: name, targets: make(map[string]bool)}
+		c.byName[name] = n
+		c.order = append(c.order, n)
+	}
+	n.targets[target] = true
+}
+
+// ranked returns the names seen on at least minCount targets, most
+// frequent first
+func (c *nameCounter) ranked(minCount int) []*learnedName {
+	var out []*learnedName
+	for _, n := range c.order {
+		if len(n.targets) >= minCount {
+			out = append(out, n)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return len(out[i].targets) > len(out[j].targets)
+	})
+	return out
+}
+
+// wordlistLearner aggregates the services and methods confirmed by
+// completed scans
+type wordlistLearner struct {
+	results  int
+	targets  map[string]bool
+	services *nameCounter
+	methods  map[string]*nameCounter // per service
+	globals  *nameCounter            // method name -> services it was found on
+	packages *nameCounter
+}
+
+func newWordlistLearner() *wordlistLearner {
+	return &wordlistLearner{
+		targets:  make(map[string]bool),
+		services: newNameCounter(),
+		methods:  make(map[string]*nameCounter),
+		globals:  newNameCounter(),
+		packages: newNameCounter(),
+	}
+}
+
+// addFile reads a scan results file written with -output. A JSON array of
+// results is accepted too.
+func (l *wordlistLearner) addFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read results: %v", err)
+	}
+	var results []ScanResult
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &results)
+	} else {
+		var result ScanResult
+		err = json.Unmarshal(trimmed, &result)
+		results = append(results, result)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse results: %v", err)
+	}
+	for _, result := range results {
+		target := result.Target
+		if target == "" {
+			target = path
+		}
+		l.addResult(&result, target)
+	}
+	return nil
+}
+
+// addResult counts the services and methods of one scan
+func (l *wordlistLearner) addResult(result *ScanResult, target string) {
+	l.results++
+	l.targets[target] = true
+
+	services := append([]string(nil), result.AvailableServices...)
+	for service := range result.MethodsFound {
+		if !stringInSlice(services, service) {
+			services = append(services, service)
+		}
+	}
+	sort.Strings(services)
+
+	for _, service := range services {
+		// Standard services are probed on every scan anyway
+		if strings.HasPrefix(service, "grpc.") || !grpcFullNamePattern.MatchString(service) {
+			continue
+		}
+		l.services.add(service, target)
+		short := service
+		if pos := strings.LastIndex(service, "."); pos > 0 {
+			l.packages.add(service[:pos], target)
+			short = service[pos+1:]
+		}
+
+		if l.methods[service] == nil {
+			l.methods[service] = newNameCounter()
+		}
+		for _, method := range result.MethodsFound[service] {
+			if !grpcIdentPattern.MatchString(method) {
+				continue
+			}
+			l.methods[service].add(method, target)
+			l.globals.add(method, short)
+		}
+	}
+}
+
+// wordlist builds the frequency-ranked wordlist. Entries are scored with the
+// number of targets they were found on.
+func (l *wordlistLearner) wordlist(minCount int) *wordlistSet {
+	set := newWordlistSet()
+	for _, service := range l.services.ranked(minCount) {
+		var methods []string
+		for _, method := range l.methods[service.name].ranked(1) {
+			methods = append(methods, method.name)
+		}
+		set.addEntry("", service.name, methods, len(service.targets))
+	}
+	set.scored = true
+
+	for _, pkg := range l.packages.ranked(minCount) {
+		set.packages = append(set.packages, pkg.name)
+	}
+	// Methods found on several different services are worth trying on all
+	for _, method := range l.globals.ranked(2) {
+		set.globalMethods = append(set.globalMethods, method.name)
+	}
+	return set
+}
+
+// runWordlistLearn implements "wordlist learn": turn the results of past
+// scans into a wordlist ranked by how often each name was found
+func runWordlistLearn(args []string) {
+	var (
+		output   = "learned_wordlist.txt"
+		minCount = 1
+		patterns []string
+	)
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-from=") {
+			patterns = append(patterns, strings.TrimPrefix(arg, "-from="))
+		} else if strings.HasPrefix(arg, "-output=") {
+			output = strings.TrimPrefix(arg, "-output=")
+		} else if strings.HasPrefix(arg, "-min-count=") {
+			minCount = parseIntFlag("-min-count", strings.TrimPrefix(arg, "-min-count="), 1)
+		} else if arg != "-from" && !strings.HasPrefix(arg, "-") {
+			// Files the shell expanded from -from results/*.json
+			patterns = append(patterns, arg)
+		}
+	}
+	if len(patterns) == 0 {
+		fmt.Println("Usage: grpc-scanner wordlist learn -from=<results.json|dir|glob>... [-output=learned_wordlist.txt] [-min-count=N]")
+		fmt.Println("\nAggregate the -output results of completed scans into a wordlist of the")
+		fmt.Println("services, methods and packages found, most frequent across targets first")
+		return
+	}
+
+	var files []string
+	for _, pattern := range patterns {
+		if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+			pattern = filepath.Join(pattern, "*.json")
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Fatalf("Invalid -from pattern %q: %v", pattern, err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		log.Fatal("No scan result files found")
+	}
+
+	learner := newWordlistLearner()
+	for _, file := range files {
+		if err := learner.addFile(file); err != nil {
+			log.Fatalf("Failed to learn from %s: %v", file, err)
+		}
+	}
+
+	set := learner.wordlist(minCount)
+	out, err := os.Create(output)
+	if err != nil {
+		log.Fatalf("Failed to create output file: %v", err)
+	}
+	defer out.Close()
+	writer := bufio.NewWriter(out)
+	set.write(writer,
+		fmt.Sprintf("Wordlist learned from %d scan results across %d targets", learner.results, len(learner.targets)),
+		"score is the number of targets a service was found on")
+	if err := writer.Flush(); err != nil {
+		log.Fatalf("Failed to write output file: %v", err)
+	}
+
+	fmt.Printf("Learned from %d scan results across %d targets into %s\n", learner.results, len(learner.targets), output)
+	fmt.Printf("  %d services, %d package prefixes, %d global methods\n",
+		len(set.entries), len(set.packages), len(set.globalMethods))
+}
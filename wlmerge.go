@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Names allowed by the gRPC protocol spec: a method is an identifier, a
+// service an identifier optionally qualified by a dotted package
+var (
+	grpcIdentPattern    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	grpcFullNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+)
+
+// wordlistSet accumulates enhanced wordlist entries from one or more files,
+// merging duplicate services and unioning their methods. Names are compared
+// exactly, since gRPC names are case-sensitive (ApiService and APIService
+// are different services); foldCase compares them case-insensitively.
+type wordlistSet struct {
+	entries       []WordlistEntry
+	globalMethods []string
+	packages      []string
+	scored        bool
+	foldCase      bool
+	stats         mergeStats
+
+	index   map[string]int             // service key -> entries index
+	methods map[string]map[string]bool // service key -> method keys
+	seen    map[string]bool            // "*method" and "@package" keys
+}
+
+// mergeStats counts what reading a wordlistSet cleaned up
+type mergeStats struct {
+	Files            int
+	Lines            int
+	Duplicates       int // service lines merged into an earlier entry
+	CaseVariants     int // of which spelled the service differently
+	MethodsAdded     int // methods a duplicate line added to an earlier entry
+	DuplicateMethods int // methods dropped as repeats
+	Normalized       int // lines rewritten for stray whitespace or empty items
+	Invalid          []string
+}
+
+func newWordlistSet() *wordlistSet {
+	return &wordlistSet{
+		index:   make(map[string]int),
+		methods: make(map[string]map[string]bool),
+		seen:    make(map[string]bool),
+	}
+}
+
+// key is the name used to detect duplicates
+func (w *wordlistSet) key(name string) string {
+	if w.foldCase {
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// readFile adds the entries of one wordlist file or builtin:NAME
+func (w *wordlistSet) readFile(path string) error {
+	file, err := openWordlist(path)
+	if err != nil {
+		return fmt.Errorf("failed to open wordlist: %v", err)
+	}
+	defer file.Close()
+	if err := w.read(file, path); err != nil {
+		return fmt.Errorf("error reading wordlist: %v", err)
+	}
+	return nil
+}
+
+// read adds the entries of an enhanced wordlist; name labels invalid lines
+func (w *wordlistSet) read(r io.Reader, name string) error {
+	w.stats.Files++
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		w.stats.Lines++
+		where := fmt.Sprintf("%s:%d", name, lineNo)
+
+		// Strip a trailing annotation such as "# score=12"
+		score := 0
+		if pos := strings.Index(line, "#"); pos > 0 {
+			if _, err := fmt.Sscanf(strings.TrimSpace(line[pos+1:]), "score=%d", &score); err == nil {
+				w.scored = true
+			}
+			line = strings.TrimSpace(line[:pos])
+		}
+
+		switch {
+		case strings.HasPrefix(line, "*"):
+			// Method-only entry
+			method := strings.TrimSpace(strings.TrimPrefix(line, "*"))
+			w.normalized(line, "*"+method)
+			if !grpcIdentPattern.MatchString(method) {
+				w.invalid(where, "method", method)
+			} else if key := "*" + w.key(method); w.seen[key] {
+				w.stats.DuplicateMethods++
+			} else {
+				w.seen[key] = true
+				w.globalMethods = append(w.globalMethods, method)
+			}
+		case strings.HasPrefix(line, "@"):
+			// Package prefix
+			pkg := strings.TrimSpace(strings.TrimPrefix(line, "@"))
+			w.normalized(line, "@"+pkg)
+			if !grpcFullNamePattern.MatchString(pkg) {
+				w.invalid(where, "package", pkg)
+			} else if key := "@" + w.key(pkg); !w.seen[key] {
+				w.seen[key] = true
+				w.packages = append(w.packages, pkg)
+			}
+		default:
+			// Service, optionally followed by :method1,method2
+			service, methodList, hasMethods := strings.Cut(line, ":")
+			service = strings.TrimSpace(service)
+			var methods []string
+			if hasMethods {
+				for _, m := range strings.Split(methodList, ",") {
+					if method := strings.TrimSpace(m); method != "" {
+						methods = append(methods, method)
+					}
+				}
+			}
+			canonical := service
+			if hasMethods {
+				canonical += ":" + strings.Join(methods, ",")
+			}
+			w.normalized(line, canonical)
+
+			if !grpcFullNamePattern.MatchString(service) {
+				w.invalid(where, "service", service)
+				continue
+			}
+			w.addEntry(where, service, methods, score)
+		}
+	}
+	return scanner.Err()
+}
+
+// addEntry merges one service line into the set. A bare service line
+// (no methods) marks the entry to be tried with the default methods too.
+func (w *wordlistSet) addEntry(where, service string, methods []string, score int) {
+	key := w.key(service)
+	idx, exists := w.index[key]
+	if !exists {
+		idx = len(w.entries)
+		w.index[key] = idx
+		w.methods[key] = make(map[string]bool)
+		w.entries = append(w.entries, WordlistEntry{Service: service, Score: score})
+	} else {
+		w.stats.Duplicates++
+		entry := &w.entries[idx]
+		if entry.Service != service {
+			w.stats.CaseVariants++
+			// Prefer the conventional UpperCamel spelling of the service
+			if name := service[strings.LastIndex(service, ".")+1:]; name[0] >= 'A' && name[0] <= 'Z' {
+				old := entry.Service[strings.LastIndex(entry.Service, ".")+1:]
+				if old[0] < 'A' || old[0] > 'Z' {
+					entry.Service = service
+				}
+			}
+		}
+		entry.Score = max(entry.Score, score)
+	}
+
+	entry := &w.entries[idx]
+	if len(methods) == 0 {
+		entry.Defaults = true
+	}
+	known := w.methods[key]
+	for _, method := range methods {
+		if !grpcIdentPattern.MatchString(method) {
+			w.invalid(where, "method", method)
+			continue
+		}
+		if known[w.key(method)] {
+			w.stats.DuplicateMethods++
+			continue
+		}
+		known[w.key(method)] = true
+		entry.Methods = append(entry.Methods, method)
+		if exists {
+			w.stats.MethodsAdded++
+		}
+	}
+}
+
+func (w *wordlistSet) normalized(line, canonical string) {
+	if line != canonical {
+		w.stats.Normalized++
+	}
+}
+
+func (w *wordlistSet) invalid(where, kind, name string) {
+	w.stats.Invalid = append(w.stats.Invalid, fmt.Sprintf("%s: invalid %s name %q", where, kind, name))
+}
+
+// sorted returns the entries, highest score first when any were annotated
+func (w *wordlistSet) sorted() []WordlistEntry {
+	entries := append([]WordlistEntry(nil), w.entries...)
+	if w.scored {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Score > entries[j].Score
+		})
+	}
+	return entries
+}
+
+// write outputs the set as an enhanced wordlist under the given comment
+// lines, keeping the first-seen order (or the score order) of the inputs
+func (w *wordlistSet) write(writer *bufio.Writer, header ...string) {
+	for _, line := range header {
+		fmt.Fprintf(writer, "# %s\n", line)
+	}
+	fmt.Fprintf(writer, "# Format: ServiceName:method1,method2,method3\n\n")
+
+	for _, entry := range w.sorted() {
+		var lines []string
+		// Keep the bare line of an entry that also lists methods, so the
+		// default methods are still tried when the output is loaded
+		if entry.Defaults || len(entry.Methods) == 0 {
+			lines = append(lines, entry.Service)
+		}
+		if len(entry.Methods) > 0 {
+			lines = append(lines, entry.Service+":"+strings.Join(entry.Methods, ","))
+		}
+		for _, line := range lines {
+			if w.scored {
+				line += fmt.Sprintf(" # score=%d", entry.Score)
+			}
+			fmt.Fprintf(writer, "%s\n", line)
+		}
+	}
+
+	if len(w.packages) > 0 {
+		fmt.Fprintf(writer, "\n# Package prefixes to try on short service names\n")
+		for _, pkg := range w.packages {
+			fmt.Fprintf(writer, "@%s\n", pkg)
+		}
+	}
+	if len(w.globalMethods) > 0 {
+		fmt.Fprintf(writer, "\n# Global methods to try on all services\n")
+		for _, method := range w.globalMethods {
+			fmt.Fprintf(writer, "*%s\n", method)
+		}
+	}
+}
+
+// runWordlistMerge implements "wordlist merge": union several wordlists
+// into one clean enhanced wordlist
+func runWordlistMerge(args []string) {
+	var (
+		output  = "merged_wordlist.txt"
+		verbose = false
+		inputs  []string
+	)
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-output=") {
+			output = strings.TrimPrefix(arg, "-output=")
+		} else if arg == "-v" {
+			verbose = true
+		} else if !strings.HasPrefix(arg, "-") {
+			inputs = append(inputs, arg)
+		}
+	}
+	if len(inputs) == 0 {
+		fmt.Println("Usage: grpc-scanner wordlist merge [-output=merged_wordlist.txt] [-v] <wordlist>...")
+		fmt.Println("\nUnion the services, methods, package prefixes and global methods of several")
+		fmt.Println("wordlists, dropping duplicates and names that are not valid gRPC identifiers")
+		return
+	}
+
+	// Unlike the scan loader, merging also folds case variants such as
+	// userservice and UserService, which hand-made lists often mix up
+	set := newWordlistSet()
+	set.foldCase = true
+	for _, input := range inputs {
+		if err := set.readFile(input); err != nil {
+			log.Fatalf("Failed to read %s: %v", input, err)
+		}
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		log.Fatalf("Failed to create output file: %v", err)
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+	set.write(writer, "Merged wordlist")
+	if err := writer.Flush(); err != nil {
+		log.Fatalf("Failed to write output file: %v", err)
+	}
+
+	st := set.stats
+	fmt.Printf("Merged %d files (%d entries) into %s\n", st.Files, st.Lines, output)
+	fmt.Printf("  %d services, %d package prefixes, %d global methods\n",
+		len(set.entries), len(set.packages), len(set.globalMethods))
+	fmt.Printf("  %d duplicate service lines merged (%d case variants), %d methods unioned in\n",
+		st.Duplicates, st.CaseVariants, st.MethodsAdded)
+	fmt.Printf("  %d duplicate methods dropped, %d lines normalized, %d invalid names rejected\n",
+		st.DuplicateMethods, st.Normalized, len(st.Invalid))
+	if verbose {
+		for _, msg := range st.Invalid {
+			fmt.Printf("  %s\n", msg)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func readWordlistSet(t *testing.T, input string, foldCase bool) *wordlistSet {
+	t.Helper()
+	set := newWordlistSet()
+	set.foldCase = foldCase
+	if err := set.read(strings.NewReader(input), "test.txt"); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return set
+}
+
+func TestWordlistSetRead(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		foldCase      bool
+		entries       []WordlistEntry
+		globalMethods []string
+		packages      []string
+		stats         mergeStats
+	}{
+		{
+			name:  "services, methods, packages and comments",
+			input: "# comment\n// comment\n\nUserService:GetUser,ListUsers\nacme.v1.OrderService\n*Ping\n@acme.v1\n",
+			entries: []WordlistEntry{
+				{Service: "UserService", Methods: []string{"GetUser", "ListUsers"}},
+				{Service: "acme.v1.OrderService", Defaults: true},
+			},
+			globalMethods: []string{"Ping"},
+			packages:      []string{"acme.v1"},
+			stats:         mergeStats{Files: 1, Lines: 4},
+		},
+		{
+			name:  "duplicate services are merged",
+			input: "UserService:GetUser\nUserService\nUserService:GetUser,DeleteUser\n*Ping\n*Ping\n",
+			entries: []WordlistEntry{
+				{Service: "UserService", Methods: []string{"GetUser", "DeleteUser"}, Defaults: true},
+			},
+			globalMethods: []string{"Ping"},
+			stats:         mergeStats{Files: 1, Lines: 5, Duplicates: 2, MethodsAdded: 1, DuplicateMethods: 2},
+		},
+		{
+			name:  "case variants stay apart without foldCase",
+			input: "ApiService:Get\nAPIService:get\n*Ping\n*ping\n",
+			entries: []WordlistEntry{
+				{Service: "ApiService", Methods: []string{"Get"}},
+				{Service: "APIService", Methods: []string{"get"}},
+			},
+			globalMethods: []string{"Ping", "ping"},
+			stats:         mergeStats{Files: 1, Lines: 4},
+		},
+		{
+			name:     "case variants merge with foldCase",
+			input:    "userService:Get\nUserService:get,List\n*Ping\n*ping\n@Acme\n@acme\n",
+			foldCase: true,
+			entries: []WordlistEntry{
+				{Service: "UserService", Methods: []string{"Get", "List"}},
+			},
+			globalMethods: []string{"Ping"},
+			packages:      []string{"Acme"},
+			stats:         mergeStats{Files: 1, Lines: 6, Duplicates: 1, CaseVariants: 1, MethodsAdded: 1, DuplicateMethods: 2},
+		},
+		{
+			name:  "score annotations keep the highest",
+			input: "UserService:GetUser # score=12\nUserService:ListUsers # score=30\nOrderService # score=5\n",
+			entries: []WordlistEntry{
+				{Service: "UserService", Methods: []string{"GetUser", "ListUsers"}, Score: 30},
+				{Service: "OrderService", Score: 5, Defaults: true},
+			},
+			stats: mergeStats{Files: 1, Lines: 3, Duplicates: 1, MethodsAdded: 1},
+		},
+		{
+			name:  "whitespace and empty items are normalized",
+			input: "  UserService : GetUser , ,ListUsers  \n* Ping\n",
+			entries: []WordlistEntry{
+				{Service: "UserService", Methods: []string{"GetUser", "ListUsers"}},
+			},
+			globalMethods: []string{"Ping"},
+			stats:         mergeStats{Files: 1, Lines: 2, Normalized: 2},
+		},
+		{
+			name:  "invalid names are skipped",
+			input: "v1/users:Get\nUserService:Get-User,GetUser\n*9lives\n@acme..v1\n",
+			entries: []WordlistEntry{
+				{Service: "UserService", Methods: []string{"GetUser"}},
+			},
+			stats: mergeStats{Files: 1, Lines: 4, Invalid: []string{
+				`test.txt:1: invalid service name "v1/users"`,
+				`test.txt:2: invalid method name "Get-User"`,
+				`test.txt:3: invalid method name "9lives"`,
+				`test.txt:4: invalid package name "acme..v1"`,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := readWordlistSet(t, tt.input, tt.foldCase)
+			if !reflect.DeepEqual(set.entries, tt.entries) {
+				t.Errorf("entries = %+v, want %+v", set.entries, tt.entries)
+			}
+			if !reflect.DeepEqual(set.globalMethods, tt.globalMethods) {
+				t.Errorf("global methods = %v, want %v", set.globalMethods, tt.globalMethods)
+			}
+			if !reflect.DeepEqual(set.packages, tt.packages) {
+				t.Errorf("packages = %v, want %v", set.packages, tt.packages)
+			}
+			if !reflect.DeepEqual(set.stats, tt.stats) {
+				t.Errorf("stats = %+v, want %+v", set.stats, tt.stats)
+			}
+		})
+	}
+}
+
+func TestWordlistSetWrite(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		foldCase bool
+		want     string
+	}{
+		{
+			name:  "bare line kept next to methods",
+			input: "UserService:GetUser\nUserService\nOrderService\n*Ping\n@acme.v1\n",
+			want: "# merged\n# Format: ServiceName:method1,method2,method3\n\n" +
+				"UserService\nUserService:GetUser\nOrderService\n" +
+				"\n# Package prefixes to try on short service names\n@acme.v1\n" +
+				"\n# Global methods to try on all services\n*Ping\n",
+		},
+		{
+			name:  "scores sort and annotate",
+			input: "OrderService:Get # score=5\nUserService # score=20\nUserService:List\n",
+			want: "# merged\n# Format: ServiceName:method1,method2,method3\n\n" +
+				"UserService # score=20\nUserService:List # score=20\nOrderService:Get # score=5\n",
+		},
+		{
+			name:  "case variants written apart without foldCase",
+			input: "apiService:Get\nApiService:List\n",
+			want: "# merged\n# Format: ServiceName:method1,method2,method3\n\n" +
+				"apiService:Get\nApiService:List\n",
+		},
+		{
+			name:     "case variants written once with foldCase",
+			input:    "apiService:Get\nApiService:List\n",
+			foldCase: true,
+			want: "# merged\n# Format: ServiceName:method1,method2,method3\n\n" +
+				"ApiService:Get,List\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := readWordlistSet(t, tt.input, tt.foldCase)
+			var out strings.Builder
+			writer := bufio.NewWriter(&out)
+			set.write(writer, "merged")
+			if err := writer.Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("write =\n%s\nwant\n%s", out.String(), tt.want)
+			}
+
+			// The output reads back to the same set
+			again := readWordlistSet(t, out.String(), tt.foldCase)
+			if !reflect.DeepEqual(again.sorted(), set.sorted()) {
+				t.Errorf("entries after a round trip = %+v, want %+v", again.sorted(), set.sorted())
+			}
+		})
+	}
+}
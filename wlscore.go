@@ -0,0 +1,80 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Sources a wordlist candidate can come from
+const (
+	sourceText        = "text"         // XService or getX in prose
+	sourceURLPath     = "url-path"     // /api/<service>/<method>
+	sourceCodeBlock   = "code-block"   // class or interface in a code sample
+	sourceOperationID = "operation-id" // OpenAPI operation
+	sourceStub        = "stub"         // generated stub, descriptor, .proto or captured call
+)
+
+// sourceWeights rates how likely a sighting from each source names a real
+// service: regex hits in prose are cheap, generated stubs are exact
+var sourceWeights = map[string]int{
+	sourceText:        1,
+	sourceURLPath:     3,
+	sourceCodeBlock:   4,
+	sourceOperationID: 8,
+	sourceStub:        10,
+}
+
+// qualifiedServicePattern matches package-qualified names like acme.v1.UserService
+var qualifiedServicePattern = regexp.MustCompile(`^(?:[a-z_][a-z0-9_]*\.)+[A-Z]\w*$`)
+
+// candidateScore tracks the sightings of one wordlist candidate
+type candidateScore struct {
+	best      int
+	sightings int
+}
+
+// addService records a sighting of a candidate service
+func (e *WordlistExtractor) addService(service, source string) {
+	e.services[service] = true
+	sc := e.scores[service]
+	if sc == nil {
+		sc = &candidateScore{}
+		e.scores[service] = sc
+	}
+	sc.sightings++
+	if weight := sourceWeights[source]; weight > sc.best {
+		sc.best = weight
+	}
+}
+
+// Score ranks a candidate: the weight of its best source, a point per extra
+// sighting (up to five), and a bonus for names shaped like gRPC services
+func (e *WordlistExtractor) Score(service string) int {
+	sc := e.scores[service]
+	if sc == nil {
+		return 0
+	}
+	score := sc.best + min(sc.sightings-1, 5)
+	if qualifiedServicePattern.MatchString(service) {
+		score += 3
+	}
+	if strings.HasSuffix(service, "Service") {
+		score++
+	}
+	if len(e.serviceMethods[service]) > 0 {
+		score++
+	}
+	return score
+}
+
+// rankServices sorts services by score, highest first, then by name
+func (e *WordlistExtractor) rankServices(services []string) {
+	sort.Slice(services, func(i, j int) bool {
+		si, sj := e.Score(services[i]), e.Score(services[j])
+		if si != sj {
+			return si > sj
+		}
+		return services[i] < services[j]
+	})
+}